@@ -3,21 +3,33 @@ package controllers
 import (
 	"codepub-service/model"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/pkg/v3/srv"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
 	"gorm.io/gorm"
 )
 
-// GetEtcdAllKeys 获取所有的keys
+// GetEtcdAllKeys 获取所有的keys，query参数：prefix（只返回该前缀下的keys）
 func GetEtcdAllKeys(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
-	// 获取etcd url
-	etcdUrl, err := model.GetEtcdUrlByName(db, name)
+	prefix := c.Query("prefix")
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -26,23 +38,19 @@ func GetEtcdAllKeys(c *gin.Context, db *gorm.DB) {
 	}
 
 	// 连接到etcd
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{etcdUrl},
-		DialTimeout: 5 * time.Second,
-	})
+	cli, release, err := etcdClients.Get(etcd)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
-	defer func(cli *clientv3.Client) {
-		_ = cli.Close()
-	}(cli)
+	defer release()
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
 	defer cancel()
 
 	// 获取所有keys
-	resp, err := cli.Get(ctx, "", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	resp, err := cli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	setEtcdEndpointHeader(c, ctx)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
@@ -61,8 +69,8 @@ func GetEtcdAllKeys(c *gin.Context, db *gorm.DB) {
 func GetEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
 	key := c.Query("key")
-	// 获取etcd url
-	etcdUrl, err := model.GetEtcdUrlByName(db, name)
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -70,23 +78,19 @@ func GetEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 		return
 	}
 	// 连接到etcd
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{etcdUrl},
-		DialTimeout: 5 * time.Second,
-	})
+	cli, release, err := etcdClients.Get(etcd)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
-	defer func(cli *clientv3.Client) {
-		_ = cli.Close()
-	}(cli)
+	defer release()
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
 	defer cancel()
 
 	// 获取指定key的value
 	resp, err := cli.Get(ctx, key)
+	setEtcdEndpointHeader(c, ctx)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
@@ -96,21 +100,46 @@ func GetEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 		c.JSON(500, gin.H{"message": "key not found"})
 		return
 	}
-	// 返回指定key的value
-	c.JSON(200, gin.H{
-		"value": string(resp.Kvs[0].Value),
-	})
+	// 返回指定key的value，mod_revision用于保存和删除时的并发校验
+	result := gin.H{
+		"value":        string(resp.Kvs[0].Value),
+		"mod_revision": resp.Kvs[0].ModRevision,
+	}
+	// key绑定了lease时返回lease及剩余ttl
+	if leaseID := resp.Kvs[0].Lease; leaseID != 0 {
+		lease := gin.H{"id": formatEtcdLeaseID(clientv3.LeaseID(leaseID))}
+		if ttl, err := cli.TimeToLive(ctx, clientv3.LeaseID(leaseID)); err == nil {
+			lease["ttl"] = ttl.TTL
+			lease["granted_ttl"] = ttl.GrantedTTL
+		}
+		result["lease"] = lease
+	}
+	c.JSON(200, result)
 }
 
-// SaveEtcdValueByKey 新增或更新key、value
+// SaveEtcdValueByKey 新增或更新key、value，表单参数：key、value、revision（可选，期望的mod_revision，0表示key必须不存在）、ttl（可选，秒）
+// 传入revision时key已被修改则返回409和当前值；传入ttl时key绑定新的lease，到期后自动删除
 func SaveEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
 	key := c.PostForm("key")
 	value := c.PostForm("value")
 	// 替换 value 中的 \n 为换行符
 	value = strings.ReplaceAll(value, "\\n", "\n")
-	// 获取etcd url
-	etcdUrl, err := model.GetEtcdUrlByName(db, name)
+	revision, hasRevision, err := parseEtcdRevision(c.PostForm("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	ttl := int64(0)
+	if s := c.PostForm("ttl"); s != "" {
+		ttl, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "ttl must be a positive integer"})
+			return
+		}
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -118,38 +147,72 @@ func SaveEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 		return
 	}
 	// 连接到etcd
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{etcdUrl},
-		DialTimeout: 5 * time.Second,
-	})
+	cli, release, err := etcdClients.Get(etcd)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
-	defer func(cli *clientv3.Client) {
-		_ = cli.Close()
-	}(cli)
+	defer release()
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
 	defer cancel()
 
+	// 设置了ttl时创建lease
+	var putOpts []clientv3.OpOption
+	var leaseID clientv3.LeaseID
+	if ttl > 0 {
+		lease, err := cli.Grant(ctx, ttl)
+		if err != nil {
+			setEtcdEndpointHeader(c, ctx)
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		leaseID = lease.ID
+		putOpts = append(putOpts, clientv3.WithLease(leaseID))
+	}
+
 	// 提交key、value，新增或更新
-	_, err = cli.Put(ctx, key, value)
+	txn := cli.Txn(ctx)
+	if hasRevision {
+		txn = txn.If(clientv3.Compare(clientv3.ModRevision(key), "=", revision))
+	}
+	resp, err := txn.Then(clientv3.OpPut(key, value, putOpts...)).Else(clientv3.OpGet(key)).Commit()
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil || !resp.Succeeded {
+		// 写入失败时回收刚创建的lease
+		if leaseID != 0 {
+			_, _ = cli.Revoke(ctx, leaseID)
+		}
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
+	if !resp.Succeeded {
+		etcdConflict(c, resp)
+		return
+	}
 
 	// 返回
-	c.JSON(http.StatusOK, gin.H{"message": "true"})
+	result := gin.H{"message": "true", "mod_revision": resp.Header.Revision}
+	if leaseID != 0 {
+		result["lease"] = formatEtcdLeaseID(leaseID)
+	}
+	c.JSON(http.StatusOK, result)
 }
 
-// DeleteEtcdValueByKey 删除指定的key
+// DeleteEtcdValueByKey 删除指定的key，query参数：key、revision（可选，期望的mod_revision）
+// 传入revision时key已被修改则返回409和当前值；删除前的value保存到回收站
 func DeleteEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
 	key := c.Query("key")
-	// 获取etcd url
-	etcdUrl, err := model.GetEtcdUrlByName(db, name)
+	revision, hasRevision, err := parseEtcdRevision(c.Query("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -157,27 +220,221 @@ func DeleteEtcdValueByKey(c *gin.Context, db *gorm.DB) {
 		return
 	}
 	// 连接到etcd
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{etcdUrl},
-		DialTimeout: 5 * time.Second,
-	})
+	cli, release, err := etcdClients.Get(etcd)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
-	defer func(cli *clientv3.Client) {
-		_ = cli.Close()
-	}(cli)
+	defer release()
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
 	defer cancel()
 
-	// 删除key
-	_, err = cli.Delete(ctx, key)
+	// 读取当前值
+	cur, err := cli.Get(ctx, key)
 	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
 		c.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
+	var kv *mvccpb.KeyValue
+	if len(cur.Kvs) > 0 {
+		kv = cur.Kvs[0]
+	}
+	if hasRevision && (kv == nil && revision != 0 || kv != nil && kv.ModRevision != revision) {
+		setEtcdEndpointHeader(c, ctx)
+		etcdConflictKV(c, kv)
+		return
+	}
+	// key不存在，无需删除
+	if kv == nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(http.StatusOK, gin.H{"message": "true"})
+		return
+	}
+
+	// 删除前放入回收站，回收站写入失败时不影响删除
+	trash := newEtcdTrash(name, kv, model.CurrentUserID(c))
+	trashErr := db.Create(&trash).Error
+	if trashErr != nil {
+		log.Printf("etcd %s: failed to save trash for %q: %v", name, key, trashErr)
+	}
+
+	// 删除key，读取后key被修改则放弃删除
+	resp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+		Then(clientv3.OpDelete(key)).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	setEtcdEndpointHeader(c, ctx)
+	if trashErr == nil && (err != nil || !resp.Succeeded) {
+		db.Delete(&trash)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if !resp.Succeeded {
+		etcdConflict(c, resp)
+		return
+	}
 	// 返回
-	c.JSON(http.StatusOK, gin.H{"message": "true"})
+	if trashErr != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "true", "trash_error": trashErr.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true", "trash": trash.ID})
+}
+
+// newEtcdTrash 创建etcd key的回收站条目，key或value不是有效的utf8时使用base64保存
+func newEtcdTrash(name string, kv *mvccpb.KeyValue, userID uint) model.Trash {
+	trash := model.Trash{
+		Kind:      model.TrashKindEtcd,
+		Instance:  name,
+		Key:       string(kv.Key),
+		Value:     string(kv.Value),
+		DeletedBy: userID,
+	}
+	if !utf8.Valid(kv.Key) || !utf8.Valid(kv.Value) {
+		trash.Key = base64.StdEncoding.EncodeToString(kv.Key)
+		trash.Value = base64.StdEncoding.EncodeToString(kv.Value)
+		trash.Encoding = model.TrashEncodingBase64
+	}
+	return trash
+}
+
+// parseEtcdRevision 解析期望的mod_revision，为空时返回false
+func parseEtcdRevision(s string) (int64, bool, error) {
+	if s == "" {
+		return 0, false, nil
+	}
+	revision, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, false, errors.New("revision must be a non-negative integer")
+	}
+	return revision, true, nil
+}
+
+// etcdConflict 比较失败时返回409和key的当前值，txn的Else分支需为OpGet
+func etcdConflict(c *gin.Context, resp *clientv3.TxnResponse) {
+	var kv *mvccpb.KeyValue
+	if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+		kv = kvs[0]
+	}
+	etcdConflictKV(c, kv)
+}
+
+// etcdConflictKV 返回409和key的当前值，kv为nil表示key不存在
+func etcdConflictKV(c *gin.Context, kv *mvccpb.KeyValue) {
+	current := gin.H{"exists": false}
+	if kv != nil {
+		current = gin.H{
+			"exists":       true,
+			"value":        string(kv.Value),
+			"mod_revision": kv.ModRevision,
+		}
+	}
+	c.JSON(http.StatusConflict, gin.H{"message": "key has been modified", "current": current})
+}
+
+// newEtcdClient 根据etcd_config创建客户端，配置了用户名或证书时启用认证和TLS
+func newEtcdClient(etcd model.Etcd) (*clientv3.Client, error) {
+	// 解密password、证书、私钥
+	if err := model.DecryptEtcdSecret(&etcd); err != nil {
+		return nil, errors.New("failed to decrypt etcd secret: " + err.Error())
+	}
+
+	endpoints, err := etcdEndpoints(etcd)
+	if err != nil {
+		return nil, err
+	}
+
+	config := clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+		Username:    etcd.Username,
+		Password:    etcd.Password,
+		// 记录每次请求实际访问的节点
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(etcdPeerInterceptor)},
+	}
+
+	// 配置TLS
+	if etcd.CACert != "" || etcd.Cert != "" || etcd.Key != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if etcd.CACert != "" {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(etcd.CACert)) {
+				return nil, errors.New("invalid etcd CA certificate")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if etcd.Cert != "" || etcd.Key != "" {
+			cert, err := tls.X509KeyPair([]byte(etcd.Cert), []byte(etcd.Key))
+			if err != nil {
+				return nil, errors.New("invalid etcd client certificate: " + err.Error())
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		config.TLS = tlsConfig
+	}
+
+	return clientv3.New(config)
+}
+
+// etcdEndpoints 获取etcd的所有节点地址，url中多个地址用逗号分隔，配置了srv时通过DNS SRV发现
+func etcdEndpoints(etcd model.Etcd) ([]string, error) {
+	var endpoints []string
+	for _, endpoint := range strings.Split(etcd.URL, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if etcd.SRV != "" {
+		srvClients, err := srv.GetClient("etcd-client", etcd.SRV, "")
+		if err != nil {
+			return nil, errors.New("failed to discover etcd endpoints: " + err.Error())
+		}
+		endpoints = append(endpoints, srvClients.Endpoints...)
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("etcd has no endpoint")
+	}
+	return endpoints, nil
+}
+
+// etcdPeerKey context中保存实际访问节点的key
+type etcdPeerKey struct{}
+
+// withEtcdPeer 返回可记录实际访问节点的context
+func withEtcdPeer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, etcdPeerKey{}, new(string))
+}
+
+// etcdPeer 获取context中记录的最近一次访问的节点
+func etcdPeer(ctx context.Context) string {
+	if addr, ok := ctx.Value(etcdPeerKey{}).(*string); ok {
+		return *addr
+	}
+	return ""
+}
+
+// etcdPeerInterceptor grpc拦截器，将实际访问的节点地址写入context
+func etcdPeerInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	addr, ok := ctx.Value(etcdPeerKey{}).(*string)
+	if !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	var p peer.Peer
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+	if p.Addr != nil {
+		*addr = p.Addr.String()
+	}
+	return err
+}
+
+// setEtcdEndpointHeader 在响应头中返回本次请求实际访问的etcd节点
+func setEtcdEndpointHeader(c *gin.Context, ctx context.Context) {
+	if addr := etcdPeer(ctx); addr != "" {
+		c.Header("X-Etcd-Endpoint", addr)
+	}
 }
@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// GetEtcdAllUser 获取etcd的用户及其角色
+func GetEtcdAllUser(c *gin.Context, db *gorm.DB) {
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	resp, err := cli.UserList(ctx)
+	if err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	users := make([]gin.H, 0, len(resp.Users))
+	for _, user := range resp.Users {
+		userResp, err := cli.UserGet(ctx, user)
+		if err != nil {
+			etcdAuthError(c, ctx, err)
+			return
+		}
+		roles := userResp.Roles
+		if roles == nil {
+			roles = []string{}
+		}
+		users = append(users, gin.H{"user": user, "roles": roles})
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateEtcdUser 新增etcd用户，表单参数：user、password（为空时创建无密码用户，只能通过证书认证）
+func CreateEtcdUser(c *gin.Context, db *gorm.DB) {
+	user := c.PostForm("user")
+	password := c.PostForm("password")
+	if user == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user is required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	var err error
+	if password == "" {
+		_, err = cli.UserAddWithOptions(ctx, user, "", &clientv3.UserAddOptions{NoPassword: true})
+	} else {
+		_, err = cli.UserAdd(ctx, user, password)
+	}
+	if err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// DeleteEtcdUser 删除etcd用户，query参数：user
+func DeleteEtcdUser(c *gin.Context, db *gorm.DB) {
+	user := c.Query("user")
+	if user == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user is required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.UserDelete(ctx, user); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// GrantEtcdUserRole 为用户授予角色，表单参数：user、role
+func GrantEtcdUserRole(c *gin.Context, db *gorm.DB) {
+	user := c.PostForm("user")
+	role := c.PostForm("role")
+	if user == "" || role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user and role are required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.UserGrantRole(ctx, user, role); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// RevokeEtcdUserRole 撤销用户的角色，query参数：user、role
+func RevokeEtcdUserRole(c *gin.Context, db *gorm.DB) {
+	user := c.Query("user")
+	role := c.Query("role")
+	if user == "" || role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user and role are required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.UserRevokeRole(ctx, user, role); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// GetEtcdAllRole 获取etcd的角色及其权限
+func GetEtcdAllRole(c *gin.Context, db *gorm.DB) {
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	resp, err := cli.RoleList(ctx)
+	if err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	roles := make([]gin.H, 0, len(resp.Roles))
+	for _, role := range resp.Roles {
+		roleResp, err := cli.RoleGet(ctx, role)
+		if err != nil {
+			etcdAuthError(c, ctx, err)
+			return
+		}
+		perms := make([]gin.H, 0, len(roleResp.Perm))
+		for _, perm := range roleResp.Perm {
+			perms = append(perms, gin.H{
+				"permType": perm.PermType.String(),
+				"key":      string(perm.Key),
+				"rangeEnd": string(perm.RangeEnd),
+			})
+		}
+		roles = append(roles, gin.H{"role": role, "permissions": perms})
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateEtcdRole 新增etcd角色，表单参数：role
+func CreateEtcdRole(c *gin.Context, db *gorm.DB) {
+	role := c.PostForm("role")
+	if role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "role is required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.RoleAdd(ctx, role); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// DeleteEtcdRole 删除etcd角色，query参数：role
+func DeleteEtcdRole(c *gin.Context, db *gorm.DB) {
+	role := c.Query("role")
+	if role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "role is required"})
+		return
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.RoleDelete(ctx, role); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// GrantEtcdRolePermission 为角色授予key范围的权限，表单参数：role、key、rangeEnd、prefix、permType（read、write、readwrite）
+// prefix为true时授予key前缀下的权限，否则rangeEnd为空时只授予单个key
+func GrantEtcdRolePermission(c *gin.Context, db *gorm.DB) {
+	role := c.PostForm("role")
+	key := c.PostForm("key")
+	if role == "" || key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "role and key are required"})
+		return
+	}
+	permType, err := parseEtcdPermType(c.PostForm("permType"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	rangeEnd := c.PostForm("rangeEnd")
+	if c.PostForm("prefix") == "true" {
+		rangeEnd = clientv3.GetPrefixRangeEnd(key)
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.RoleGrantPermission(ctx, role, key, rangeEnd, permType); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// RevokeEtcdRolePermission 撤销角色在key范围上的权限，query参数：role、key、rangeEnd、prefix
+func RevokeEtcdRolePermission(c *gin.Context, db *gorm.DB) {
+	role := c.Query("role")
+	key := c.Query("key")
+	if role == "" || key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "role and key are required"})
+		return
+	}
+	rangeEnd := c.Query("rangeEnd")
+	if c.Query("prefix") == "true" {
+		rangeEnd = clientv3.GetPrefixRangeEnd(key)
+	}
+	cli, ctx, cancel, ok := etcdAuthClient(c, db)
+	if !ok {
+		return
+	}
+	defer cancel()
+
+	if _, err := cli.RoleRevokePermission(ctx, role, key, rangeEnd); err != nil {
+		etcdAuthError(c, ctx, err)
+		return
+	}
+	setEtcdEndpointHeader(c, ctx)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// etcdAuthClient 获取etcd客户端和带超时的context，调用方需调用返回的cancel释放客户端，失败时已写入响应
+func etcdAuthClient(c *gin.Context, db *gorm.DB) (*clientv3.Client, context.Context, context.CancelFunc, bool) {
+	name := c.Param("name")
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return nil, nil, nil, false
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return nil, nil, nil, false
+	}
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 10*time.Second)
+	// cancel时同时释放客户端
+	return cli, ctx, func() {
+		cancel()
+		release()
+	}, true
+}
+
+// etcdAuthError 返回auth接口的错误，用户或角色不存在时返回404，已存在时返回409
+func etcdAuthError(c *gin.Context, ctx context.Context, err error) {
+	setEtcdEndpointHeader(c, ctx)
+	switch {
+	case errors.Is(err, rpctypes.ErrUserNotFound), errors.Is(err, rpctypes.ErrRoleNotFound),
+		errors.Is(err, rpctypes.ErrPermissionNotGranted), errors.Is(err, rpctypes.ErrRoleNotGranted):
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+	case errors.Is(err, rpctypes.ErrUserAlreadyExist), errors.Is(err, rpctypes.ErrRoleAlreadyExist):
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+	default:
+		c.JSON(500, gin.H{"message": err.Error()})
+	}
+}
+
+// parseEtcdPermType 解析权限类型
+func parseEtcdPermType(s string) (clientv3.PermissionType, error) {
+	switch s {
+	case "read":
+		return clientv3.PermissionType(clientv3.PermRead), nil
+	case "write":
+		return clientv3.PermissionType(clientv3.PermWrite), nil
+	case "readwrite":
+		return clientv3.PermissionType(clientv3.PermReadWrite), nil
+	default:
+		return 0, errors.New("permType must be one of read, write, readwrite")
+	}
+}
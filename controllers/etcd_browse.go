@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdBrowseItem 浏览结果中的单项，dir为true时key为目录前缀
+type etcdBrowseItem struct {
+	Key   string `json:"key"`
+	Dir   bool   `json:"dir"`
+	Count *int64 `json:"count,omitempty"`
+}
+
+// BrowseEtcdKeys 按前缀分页浏览key，query参数：prefix、separator（默认/）、dir、count、limit（默认100）、continue
+// dir为true时按separator将下一级合并为目录；count为true时返回每个目录下的key数量；continue为上一页返回的续传key
+func BrowseEtcdKeys(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	prefix := c.Query("prefix")
+	separator := c.DefaultQuery("separator", "/")
+	dir := c.Query("dir") == "true"
+	withCount := c.Query("count") == "true"
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be between 1 and 1000"})
+		return
+	}
+	if dir && separator == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "separator is required in dir mode"})
+		return
+	}
+	start := prefix
+	if cont := c.Query("continue"); cont != "" {
+		if !strings.HasPrefix(cont, prefix) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "continue key is outside of prefix"})
+			return
+		}
+		start = cont
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 10*time.Second)
+	defer cancel()
+
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	// etcd不允许空key，从最小key开始
+	if start == "" {
+		start = "\x00"
+	}
+	items := make([]etcdBrowseItem, 0, limit)
+	more := false
+	for {
+		resp, err := cli.Get(ctx, start, clientv3.WithRange(end), clientv3.WithKeysOnly(),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend), clientv3.WithLimit(int64(limit-len(items)+1)))
+		if err != nil {
+			setEtcdEndpointHeader(c, ctx)
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		if len(resp.Kvs) == 0 {
+			break
+		}
+		if len(items) >= limit {
+			more = true
+			break
+		}
+
+		jumped := false
+		for _, kv := range resp.Kvs {
+			if len(items) >= limit {
+				more = true
+				break
+			}
+			key := string(kv.Key)
+			if dir {
+				if idx := strings.Index(key[len(prefix):], separator); idx >= 0 {
+					// 合并为目录后跳过该目录下的其余key
+					child := key[:len(prefix)+idx+len(separator)]
+					items = append(items, etcdBrowseItem{Key: child, Dir: true})
+					start = clientv3.GetPrefixRangeEnd(child)
+					jumped = true
+					break
+				}
+			}
+			items = append(items, etcdBrowseItem{Key: key})
+			start = key + "\x00"
+		}
+		if more || (!jumped && !resp.More) {
+			break
+		}
+		// 目录的range end可能已超出前缀范围
+		if end != "\x00" && start >= end {
+			break
+		}
+	}
+
+	// 目录下的key数量
+	if withCount {
+		for i := range items {
+			if !items[i].Dir {
+				continue
+			}
+			resp, err := cli.Get(ctx, items[i].Key, clientv3.WithPrefix(), clientv3.WithCountOnly())
+			if err != nil {
+				setEtcdEndpointHeader(c, ctx)
+				c.JSON(500, gin.H{"message": err.Error()})
+				return
+			}
+			count := resp.Count
+			items[i].Count = &count
+		}
+	}
+	setEtcdEndpointHeader(c, ctx)
+
+	next := ""
+	if more {
+		next = start
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"prefix":   prefix,
+		"items":    items,
+		"more":     more,
+		"continue": next,
+	})
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// etcdClientIdleTimeout 客户端空闲超过该时间后关闭
+	etcdClientIdleTimeout = 10 * time.Minute
+	// etcdClientCheckInterval 空闲客户端健康检查间隔
+	etcdClientCheckInterval = time.Minute
+)
+
+// etcdClients 按etcd名称复用的客户端
+var etcdClients = newEtcdClientManager()
+
+// etcdPooledClient 连接池中的客户端
+type etcdPooledClient struct {
+	cli         *clientv3.Client
+	fingerprint string
+	lastUsed    time.Time
+	refs        int  // 正在使用的请求数
+	retired     bool // 已从池中移除，引用归零后关闭
+}
+
+// etcdDialCall 正在进行的拨号，同名的并发请求等待同一次拨号
+type etcdDialCall struct {
+	fingerprint string
+	done        chan struct{}
+	err         error
+	invalidated bool // 拨号期间配置被更新或删除，拨号结果不放入池中
+}
+
+// etcdClientManager etcd客户端管理，按名称缓存客户端，配置变化时重建
+// 客户端按引用计数使用，被替换、失效或空闲淘汰时等待正在使用的请求释放后才关闭
+type etcdClientManager struct {
+	mu      sync.Mutex
+	clients map[string]*etcdPooledClient
+	dialing map[string]*etcdDialCall
+	dial    func(etcd model.Etcd) (*clientv3.Client, error)
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newEtcdClientManager() *etcdClientManager {
+	m := &etcdClientManager{
+		clients: make(map[string]*etcdPooledClient),
+		dialing: make(map[string]*etcdDialCall),
+		dial:    newEtcdClient,
+		done:    make(chan struct{}),
+	}
+	// etcd_config更新或删除时关闭对应客户端
+	model.RegisterEtcdConfigListener(m.Invalidate)
+	go m.checkLoop()
+	return m
+}
+
+// Get 获取etcd客户端，配置与缓存的客户端不一致时重新创建
+// 使用完后必须调用返回的release，释放前客户端不会被关闭
+func (m *etcdClientManager) Get(etcd model.Etcd) (*clientv3.Client, func(), error) {
+	fingerprint := etcdFingerprint(etcd)
+	for {
+		m.mu.Lock()
+		var retired *clientv3.Client
+		if pooled, ok := m.clients[etcd.Name]; ok {
+			if pooled.fingerprint == fingerprint {
+				pooled.refs++
+				pooled.lastUsed = time.Now()
+				m.mu.Unlock()
+				return pooled.cli, m.release(pooled), nil
+			}
+			// 配置已变化，旧客户端等待正在使用的请求释放后关闭
+			retired = m.retireLocked(etcd.Name, pooled)
+		}
+
+		// 已有同名拨号时等待其完成后重新获取，拨号不持有锁，避免不可达的集群阻塞其他请求
+		if call, ok := m.dialing[etcd.Name]; ok {
+			m.mu.Unlock()
+			closeEtcdClient(retired)
+			<-call.done
+			if call.err != nil && call.fingerprint == fingerprint {
+				return nil, nil, call.err
+			}
+			continue
+		}
+		call := &etcdDialCall{fingerprint: fingerprint, done: make(chan struct{})}
+		m.dialing[etcd.Name] = call
+		m.mu.Unlock()
+		closeEtcdClient(retired)
+
+		cli, err := m.dial(etcd)
+
+		m.mu.Lock()
+		delete(m.dialing, etcd.Name)
+		call.err = err
+		var pooled *etcdPooledClient
+		if err == nil {
+			pooled = &etcdPooledClient{cli: cli, fingerprint: fingerprint, lastUsed: time.Now(), refs: 1}
+			if call.invalidated {
+				pooled.retired = true
+			} else {
+				m.clients[etcd.Name] = pooled
+			}
+		}
+		close(call.done)
+		m.mu.Unlock()
+		if err != nil {
+			return nil, nil, err
+		}
+		return cli, m.release(pooled), nil
+	}
+}
+
+// release 返回释放客户端引用的函数，重复调用只生效一次
+func (m *etcdClientManager) release(pooled *etcdPooledClient) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			pooled.refs--
+			pooled.lastUsed = time.Now()
+			closeNow := pooled.retired && pooled.refs == 0
+			m.mu.Unlock()
+			if closeNow {
+				closeEtcdClient(pooled.cli)
+			}
+		})
+	}
+}
+
+// retireLocked 从池中移除客户端，无引用时返回需要关闭的客户端，已移除过的返回nil，调用方需持有锁
+func (m *etcdClientManager) retireLocked(name string, pooled *etcdPooledClient) *clientv3.Client {
+	if pooled.retired {
+		return nil
+	}
+	if current, ok := m.clients[name]; ok && current == pooled {
+		delete(m.clients, name)
+	}
+	pooled.retired = true
+	if pooled.refs == 0 {
+		return pooled.cli
+	}
+	return nil
+}
+
+// Invalidate 移除指定名称的客户端，正在使用时等待释放后关闭
+func (m *etcdClientManager) Invalidate(name string) {
+	m.mu.Lock()
+	var retired *clientv3.Client
+	if pooled, ok := m.clients[name]; ok {
+		retired = m.retireLocked(name, pooled)
+	}
+	if call, ok := m.dialing[name]; ok {
+		call.invalidated = true
+	}
+	m.mu.Unlock()
+	closeEtcdClient(retired)
+}
+
+// Close 关闭所有客户端并停止健康检查，正在使用的客户端在释放后关闭
+func (m *etcdClientManager) Close() {
+	m.once.Do(func() {
+		close(m.done)
+	})
+	m.mu.Lock()
+	var retired []*clientv3.Client
+	for name, pooled := range m.clients {
+		if cli := m.retireLocked(name, pooled); cli != nil {
+			retired = append(retired, cli)
+		}
+	}
+	m.mu.Unlock()
+	for _, cli := range retired {
+		closeEtcdClient(cli)
+	}
+}
+
+// checkLoop 定期关闭长时间空闲的客户端，并对空闲客户端做健康检查
+func (m *etcdClientManager) checkLoop() {
+	ticker := time.NewTicker(etcdClientCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+func (m *etcdClientManager) check() {
+	// 复制一份待检查的客户端，避免检查期间持有锁；正在使用的客户端不做淘汰和检查
+	m.mu.Lock()
+	var retired []*clientv3.Client
+	idle := make(map[string]*etcdPooledClient)
+	for name, pooled := range m.clients {
+		if pooled.refs > 0 {
+			continue
+		}
+		if time.Since(pooled.lastUsed) >= etcdClientIdleTimeout {
+			if cli := m.retireLocked(name, pooled); cli != nil {
+				retired = append(retired, cli)
+			}
+			continue
+		}
+		if time.Since(pooled.lastUsed) >= etcdClientCheckInterval {
+			idle[name] = pooled
+		}
+	}
+	m.mu.Unlock()
+	for _, cli := range retired {
+		closeEtcdClient(cli)
+	}
+
+	for name, pooled := range idle {
+		if etcdClientHealthy(pooled.cli) {
+			continue
+		}
+		log.Printf("etcd client %s is unhealthy, closing", name)
+		m.mu.Lock()
+		cli := m.retireLocked(name, pooled)
+		m.mu.Unlock()
+		closeEtcdClient(cli)
+	}
+}
+
+// closeEtcdClient 关闭客户端，cli为nil时忽略
+func closeEtcdClient(cli *clientv3.Client) {
+	if cli != nil {
+		_ = cli.Close()
+	}
+}
+
+// etcdClientHealthy 任一节点状态正常即认为客户端可用
+func etcdClientHealthy(cli *clientv3.Client) bool {
+	for _, endpoint := range cli.Endpoints() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		_, err := cli.Status(ctx, endpoint)
+		cancel()
+		if err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// etcdFingerprint etcd_config的指纹，用于判断配置是否变化
+func etcdFingerprint(etcd model.Etcd) string {
+	data, _ := json.Marshal(etcd)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CloseEtcdClients 关闭所有缓存的etcd客户端，服务退出时调用
+func CloseEtcdClients() {
+	etcdClients.Close()
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestEtcdClientManager 不注册配置监听和健康检查的管理器，dial创建不会立即连接的客户端
+func newTestEtcdClientManager(dial func(etcd model.Etcd) (*clientv3.Client, error)) *etcdClientManager {
+	if dial == nil {
+		dial = dialTestEtcdClient
+	}
+	return &etcdClientManager{
+		clients: make(map[string]*etcdPooledClient),
+		dialing: make(map[string]*etcdDialCall),
+		dial:    dial,
+		done:    make(chan struct{}),
+	}
+}
+
+func dialTestEtcdClient(etcd model.Etcd) (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:1"}})
+}
+
+func etcdClientClosed(cli *clientv3.Client) bool {
+	return cli.Ctx().Err() != nil
+}
+
+func TestEtcdClientManagerReuse(t *testing.T) {
+	var dials int32
+	m := newTestEtcdClientManager(func(etcd model.Etcd) (*clientv3.Client, error) {
+		atomic.AddInt32(&dials, 1)
+		return dialTestEtcdClient(etcd)
+	})
+	defer m.Close()
+
+	etcd := model.Etcd{Name: "a", URL: "127.0.0.1:1"}
+	cli1, release1, err := m.Get(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli2, release2, err := m.Get(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1()
+	release2()
+	if cli1 != cli2 {
+		t.Error("same config should reuse the client")
+	}
+	if dials != 1 {
+		t.Errorf("dials = %d, want 1", dials)
+	}
+}
+
+func TestEtcdClientManagerReplaceWaitsForRelease(t *testing.T) {
+	m := newTestEtcdClientManager(nil)
+	defer m.Close()
+
+	old, release, err := m.Get(model.Etcd{Name: "a", URL: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 配置变化时创建新客户端，旧客户端仍在使用不能关闭
+	cli, releaseNew, err := m.Get(model.Etcd{Name: "a", URL: "127.0.0.1:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseNew()
+	if cli == old {
+		t.Fatal("changed config should create a new client")
+	}
+	if etcdClientClosed(old) {
+		t.Fatal("replaced client closed while in use")
+	}
+	release()
+	if !etcdClientClosed(old) {
+		t.Error("replaced client should be closed after release")
+	}
+	// 重复release不影响引用计数
+	release()
+	if etcdClientClosed(cli) {
+		t.Error("current client closed by duplicate release")
+	}
+}
+
+func TestEtcdClientManagerInvalidateWaitsForRelease(t *testing.T) {
+	m := newTestEtcdClientManager(nil)
+	defer m.Close()
+
+	etcd := model.Etcd{Name: "a", URL: "127.0.0.1:1"}
+	cli, release, err := m.Get(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Invalidate("a")
+	if etcdClientClosed(cli) {
+		t.Fatal("invalidated client closed while in use")
+	}
+	release()
+	if !etcdClientClosed(cli) {
+		t.Error("invalidated client should be closed after release")
+	}
+
+	// 失效后重新获取会创建新客户端
+	cli2, release2, err := m.Get(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release2()
+	if cli2 == cli {
+		t.Error("invalidated client should not be reused")
+	}
+}
+
+func TestEtcdClientManagerIdleEviction(t *testing.T) {
+	m := newTestEtcdClientManager(nil)
+	defer m.Close()
+
+	busy, releaseBusy, err := m.Get(model.Etcd{Name: "busy", URL: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, releaseIdle, err := m.Get(model.Etcd{Name: "idle", URL: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseIdle()
+
+	// 两个客户端都超过空闲时间，只有未被使用的会被淘汰
+	m.mu.Lock()
+	for _, pooled := range m.clients {
+		pooled.lastUsed = time.Now().Add(-2 * etcdClientIdleTimeout)
+	}
+	m.mu.Unlock()
+	m.check()
+
+	if !etcdClientClosed(idle) {
+		t.Error("idle client should be evicted")
+	}
+	if etcdClientClosed(busy) {
+		t.Error("client in use should not be evicted")
+	}
+	releaseBusy()
+	m.mu.Lock()
+	_, ok := m.clients["busy"]
+	m.mu.Unlock()
+	if !ok {
+		t.Error("released client should stay in the pool")
+	}
+}
+
+func TestEtcdClientManagerSingleDial(t *testing.T) {
+	var dials int32
+	unblock := make(chan struct{})
+	m := newTestEtcdClientManager(func(etcd model.Etcd) (*clientv3.Client, error) {
+		atomic.AddInt32(&dials, 1)
+		<-unblock
+		return dialTestEtcdClient(etcd)
+	})
+	defer m.Close()
+
+	etcd := model.Etcd{Name: "a", URL: "127.0.0.1:1"}
+	var wg sync.WaitGroup
+	clients := make([]*clientv3.Client, 5)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cli, release, err := m.Get(etcd)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			clients[i] = cli
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	if dials != 1 {
+		t.Errorf("dials = %d, want 1", dials)
+	}
+	for _, cli := range clients {
+		if cli != clients[0] {
+			t.Error("concurrent Get should share one client")
+		}
+	}
+}
+
+func TestEtcdClientManagerDialDoesNotBlockOthers(t *testing.T) {
+	unblock := make(chan struct{})
+	m := newTestEtcdClientManager(func(etcd model.Etcd) (*clientv3.Client, error) {
+		if etcd.Name == "slow" {
+			<-unblock
+			return nil, errors.New("unreachable")
+		}
+		return dialTestEtcdClient(etcd)
+	})
+	defer m.Close()
+
+	slowDone := make(chan error)
+	go func() {
+		_, _, err := m.Get(model.Etcd{Name: "slow"})
+		slowDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, release, err := m.Get(model.Etcd{Name: "fast", URL: "127.0.0.1:1"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		release()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dialing one cluster blocked Get for another")
+	}
+
+	close(unblock)
+	if err := <-slowDone; err == nil {
+		t.Error("expected dial error")
+	}
+}
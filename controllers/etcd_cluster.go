@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdMemberStatus 成员及其节点状态
+type etcdMemberStatus struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peer_urls"`
+	ClientURLs []string `json:"client_urls"`
+	IsLearner  bool     `json:"is_learner"`
+	Endpoints  []gin.H  `json:"endpoints"`
+}
+
+// etcdStatusTimeout 查询单个节点状态的超时时间，节点不可达时不影响其他节点
+const etcdStatusTimeout = 5 * time.Second
+
+// GetEtcdCluster 获取etcd集群成员、各节点状态（版本、DB大小、leader、raft index）和当前告警
+// 各节点状态并发查询，每个节点单独超时，失败时记录在该节点的error中；告警查询失败时返回alarm_error
+func GetEtcdCluster(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 10*time.Second)
+	defer cancel()
+
+	// 成员列表
+	memberResp, err := cli.MemberList(ctx)
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// 各节点状态
+	members := make([]etcdMemberStatus, len(memberResp.Members))
+	leaders := make([][]uint64, len(memberResp.Members))
+	var wg sync.WaitGroup
+	for i, m := range memberResp.Members {
+		members[i] = etcdMemberStatus{
+			ID:         strconv.FormatUint(m.ID, 16),
+			Name:       m.Name,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: m.ClientURLs,
+			IsLearner:  m.IsLearner,
+			Endpoints:  make([]gin.H, len(m.ClientURLs)),
+		}
+		leaders[i] = make([]uint64, len(m.ClientURLs))
+		for j, endpoint := range m.ClientURLs {
+			wg.Add(1)
+			go func(i, j int, endpoint string) {
+				defer wg.Done()
+				members[i].Endpoints[j], leaders[i][j] = getEtcdEndpointStatus(cli, endpoint)
+			}(i, j, endpoint)
+		}
+	}
+	wg.Wait()
+	leader := uint64(0)
+	for _, ids := range leaders {
+		for _, id := range ids {
+			if id != 0 {
+				leader = id
+			}
+		}
+	}
+
+	result := gin.H{
+		"cluster_id": strconv.FormatUint(memberResp.Header.ClusterId, 16),
+		"leader":     strconv.FormatUint(leader, 16),
+		"members":    members,
+	}
+
+	// 告警，查询失败时仍返回成员和节点状态
+	alarmCtx, alarmCancel := context.WithTimeout(context.Background(), etcdStatusTimeout)
+	defer alarmCancel()
+	alarms := make([]gin.H, 0)
+	alarmResp, err := cli.AlarmList(alarmCtx)
+	if err != nil {
+		result["alarm_error"] = err.Error()
+	} else {
+		for _, alarm := range alarmResp.Alarms {
+			alarms = append(alarms, gin.H{
+				"member_id": strconv.FormatUint(alarm.MemberID, 16),
+				"alarm":     alarm.Alarm.String(),
+			})
+		}
+	}
+	result["alarms"] = alarms
+	c.JSON(http.StatusOK, result)
+}
+
+// getEtcdEndpointStatus 使用单独的超时查询节点状态，返回状态信息和该节点看到的leader
+func getEtcdEndpointStatus(cli *clientv3.Client, endpoint string) (gin.H, uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdStatusTimeout)
+	defer cancel()
+	status, err := cli.Status(ctx, endpoint)
+	if err != nil {
+		return gin.H{"endpoint": endpoint, "healthy": false, "error": err.Error()}, 0
+	}
+	return gin.H{
+		"endpoint":           endpoint,
+		"healthy":            len(status.Errors) == 0,
+		"version":            status.Version,
+		"db_size":            status.DbSize,
+		"db_size_in_use":     status.DbSizeInUse,
+		"leader":             strconv.FormatUint(status.Leader, 16),
+		"is_leader":          status.Leader == status.Header.MemberId,
+		"raft_index":         status.RaftIndex,
+		"raft_term":          status.RaftTerm,
+		"raft_applied_index": status.RaftAppliedIndex,
+		"errors":             status.Errors,
+	}, status.Leader
+}
+
+// DefragmentEtcd 碎片整理，表单参数：endpoint（可选，必须为成员的client url或配置中的地址，默认整理所有成员），仅管理员可用
+func DefragmentEtcd(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// 碎片整理耗时较长
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Minute)
+	defer cancel()
+
+	memberResp, err := cli.MemberList(ctx)
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	endpoints := []string{}
+	for _, m := range memberResp.Members {
+		endpoints = append(endpoints, m.ClientURLs...)
+	}
+	if endpoint := c.PostForm("endpoint"); endpoint != "" {
+		// 只允许集群成员或配置中的节点，避免使用该实例的凭据和证书连接任意地址
+		if !isEtcdClusterEndpoint(endpoint, endpoints, cli.Endpoints()) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "endpoint is not a member of the cluster: " + endpoint})
+			return
+		}
+		endpoints = []string{endpoint}
+	}
+
+	// 逐个节点整理，避免同时阻塞整个集群
+	results := make([]gin.H, 0, len(endpoints))
+	failed := false
+	for _, endpoint := range endpoints {
+		if _, err := cli.Defragment(ctx, endpoint); err != nil {
+			failed = true
+			results = append(results, gin.H{"endpoint": endpoint, "error": err.Error()})
+			continue
+		}
+		results = append(results, gin.H{"endpoint": endpoint, "message": "true"})
+	}
+	status := http.StatusOK
+	if failed {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{"results": results})
+}
+
+// isEtcdClusterEndpoint 判断endpoint是否为集群成员的client url或客户端配置的地址
+func isEtcdClusterEndpoint(endpoint string, memberEndpoints []string, clientEndpoints []string) bool {
+	for _, list := range [][]string{memberEndpoints, clientEndpoints} {
+		for _, e := range list {
+			if e == endpoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CompactEtcd 压缩历史版本，表单参数：revision（必填，不能超过当前revision）、physical（为true时等待物理压缩完成），仅管理员可用
+// 压缩后该revision之前的历史版本无法再查看和回滚，因此不提供默认值
+func CompactEtcd(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	revision, err := strconv.ParseInt(c.PostForm("revision"), 10, 64)
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "revision is required and must be a positive integer"})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Minute)
+	defer cancel()
+
+	// 不能压缩到未来的revision
+	resp, err := cli.Get(ctx, "\x00", clientv3.WithCountOnly())
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if revision > resp.Header.Revision {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message":  fmt.Sprintf("revision %d is greater than current revision %d", revision, resp.Header.Revision),
+			"revision": resp.Header.Revision,
+		})
+		return
+	}
+
+	var opts []clientv3.CompactOption
+	if c.PostForm("physical") == "true" {
+		opts = append(opts, clientv3.WithCompactPhysical())
+	}
+	_, err = cli.Compact(ctx, revision, opts...)
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true", "revision": revision})
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdRevision key的一个历史版本
+// etcd的mvcc不记录写入时间，历史版本中没有时间戳，按mod_revision排序
+type etcdRevision struct {
+	Value          string `json:"value"`
+	ModRevision    int64  `json:"mod_revision"`
+	CreateRevision int64  `json:"create_revision"`
+	Version        int64  `json:"version"`
+	Lease          int64  `json:"lease"`
+}
+
+// GetEtcdKeyHistory 获取key的历史版本，从最新版本按mod_revision倒序遍历，直到key被创建或历史被压缩，query参数：key、limit（默认20）
+func GetEtcdKeyHistory(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "key is required"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be between 1 and 500"})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 10*time.Second)
+	defer cancel()
+
+	// 最新版本
+	resp, err := cli.Get(ctx, key)
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if len(resp.Kvs) == 0 {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(http.StatusNotFound, gin.H{"message": "key not found"})
+		return
+	}
+
+	kv := resp.Kvs[0]
+	history := []etcdRevision{{
+		Value:          string(kv.Value),
+		ModRevision:    kv.ModRevision,
+		CreateRevision: kv.CreateRevision,
+		Version:        kv.Version,
+		Lease:          kv.Lease,
+	}}
+	compacted := false
+	// 在上一个版本之前的revision读取key，得到更早的版本
+	for len(history) < limit && kv.Version > 1 {
+		resp, err := cli.Get(ctx, key, clientv3.WithRev(kv.ModRevision-1))
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			compacted = true
+			break
+		}
+		if err != nil {
+			setEtcdEndpointHeader(c, ctx)
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		if len(resp.Kvs) == 0 {
+			break
+		}
+		kv = resp.Kvs[0]
+		history = append(history, etcdRevision{
+			Value:          string(kv.Value),
+			ModRevision:    kv.ModRevision,
+			CreateRevision: kv.CreateRevision,
+			Version:        kv.Version,
+			Lease:          kv.Lease,
+		})
+	}
+	setEtcdEndpointHeader(c, ctx)
+
+	c.JSON(http.StatusOK, gin.H{
+		"key":       key,
+		"revision":  resp.Header.Revision,
+		"compacted": compacted,
+		"history":   history,
+	})
+}
+
+// RollbackEtcdKey 将key回滚到指定历史版本的value，表单参数：key、revision（要恢复的历史mod_revision）、
+// expectedRevision（客户端读到的当前mod_revision，key已删除时为0，必填）
+// 写入时校验当前mod_revision与expectedRevision一致，否则返回409
+func RollbackEtcdKey(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	key := c.PostForm("key")
+	revision, expected, err := parseEtcdRollbackForm(key, c.PostForm("revision"), c.PostForm("expectedRevision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
+	defer cancel()
+
+	// 读取历史版本
+	old, err := cli.Get(ctx, key, clientv3.WithRev(revision))
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(http.StatusGone, gin.H{"message": "revision has been compacted"})
+		return
+	}
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if len(old.Kvs) == 0 || old.Kvs[0].ModRevision != revision {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(http.StatusNotFound, gin.H{"message": "key has no version at this revision"})
+		return
+	}
+
+	// 当前mod_revision未变化时写回历史value
+	txnResp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", expected)).
+		Then(clientv3.OpPut(key, string(old.Kvs[0].Value))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if !txnResp.Succeeded {
+		etcdConflict(c, txnResp)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "true",
+		"mod_revision": txnResp.Header.Revision,
+	})
+}
+
+// parseEtcdRollbackForm 解析回滚参数，返回要恢复的revision和期望的当前mod_revision
+// expectedRevision必须由客户端提供，服务端读取当前版本作为条件会使乐观锁校验失去意义
+func parseEtcdRollbackForm(key string, revision string, expectedRevision string) (int64, int64, error) {
+	if key == "" {
+		return 0, 0, errors.New("key is required")
+	}
+	rev, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil || rev <= 0 {
+		return 0, 0, errors.New("revision must be a positive integer")
+	}
+	expected, ok, err := parseEtcdRevision(expectedRevision)
+	if err != nil {
+		return 0, 0, errors.New("expectedRevision must be a non-negative integer")
+	}
+	if !ok {
+		return 0, 0, errors.New("expectedRevision is required")
+	}
+	return rev, expected, nil
+}
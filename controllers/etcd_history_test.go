@@ -0,0 +1,38 @@
+package controllers
+
+import "testing"
+
+func TestParseEtcdRollbackForm(t *testing.T) {
+	tests := []struct {
+		name             string
+		key              string
+		revision         string
+		expectedRevision string
+		wantRevision     int64
+		wantExpected     int64
+		wantErr          bool
+	}{
+		{name: "valid", key: "/a", revision: "5", expectedRevision: "9", wantRevision: 5, wantExpected: 9},
+		{name: "key deleted", key: "/a", revision: "5", expectedRevision: "0", wantRevision: 5, wantExpected: 0},
+		{name: "missing expectedRevision", key: "/a", revision: "5", wantErr: true},
+		{name: "invalid expectedRevision", key: "/a", revision: "5", expectedRevision: "x", wantErr: true},
+		{name: "negative expectedRevision", key: "/a", revision: "5", expectedRevision: "-1", wantErr: true},
+		{name: "missing key", revision: "5", expectedRevision: "9", wantErr: true},
+		{name: "missing revision", key: "/a", expectedRevision: "9", wantErr: true},
+		{name: "zero revision", key: "/a", revision: "0", expectedRevision: "9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, expected, err := parseEtcdRollbackForm(tt.key, tt.revision, tt.expectedRevision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if revision != tt.wantRevision || expected != tt.wantExpected {
+				t.Errorf("got (%d, %d), want (%d, %d)", revision, expected, tt.wantRevision, tt.wantExpected)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdLease lease信息，id使用16进制字符串，与etcdctl一致且避免前端丢失精度
+type etcdLease struct {
+	ID         string   `json:"id"`
+	TTL        int64    `json:"ttl"`
+	GrantedTTL int64    `json:"granted_ttl"`
+	Keys       []string `json:"keys,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// etcdLeaseConcurrency 并发查询lease ttl的数量
+const etcdLeaseConcurrency = 16
+
+// GetEtcdAllLease 获取所有存活的lease及剩余ttl和绑定的key，query参数：keys（为false时不返回绑定的key，减少lease较多时的开销）
+// 每个lease的ttl并发查询，单个lease查询失败时返回error而不影响其他lease
+func GetEtcdAllLease(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	withKeys := c.Query("keys") != "false"
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	baseCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ctx := withEtcdPeer(baseCtx)
+
+	resp, err := cli.Leases(ctx)
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	var opts []clientv3.LeaseOption
+	if withKeys {
+		opts = append(opts, clientv3.WithAttachedKeys())
+	}
+	results := make([]*etcdLease, len(resp.Leases))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, etcdLeaseConcurrency)
+	for i, status := range resp.Leases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id clientv3.LeaseID) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// 并发请求不记录访问节点，避免同时写入ctx中的节点地址
+			ttl, err := cli.TimeToLive(baseCtx, id, opts...)
+			// 遍历期间lease可能已过期
+			if errors.Is(err, rpctypes.ErrLeaseNotFound) || (err == nil && ttl.TTL == -1) {
+				return
+			}
+			lease := &etcdLease{ID: formatEtcdLeaseID(id)}
+			if err != nil {
+				lease.Error = err.Error()
+				results[i] = lease
+				return
+			}
+			lease.TTL = ttl.TTL
+			lease.GrantedTTL = ttl.GrantedTTL
+			if withKeys {
+				lease.Keys = []string{}
+				for _, key := range ttl.Keys {
+					lease.Keys = append(lease.Keys, string(key))
+				}
+			}
+			results[i] = lease
+		}(i, status.ID)
+	}
+	wg.Wait()
+	setEtcdEndpointHeader(c, ctx)
+
+	leases := make([]etcdLease, 0, len(results))
+	for _, lease := range results {
+		if lease != nil {
+			leases = append(leases, *lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].TTL < leases[j].TTL
+	})
+	c.JSON(http.StatusOK, leases)
+}
+
+// KeepAliveEtcdLease 续约一次lease，表单参数：id
+func KeepAliveEtcdLease(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	leaseID, err := parseEtcdLeaseID(c.PostForm("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.KeepAliveOnce(ctx, leaseID)
+	setEtcdEndpointHeader(c, ctx)
+	if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "lease not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true", "id": formatEtcdLeaseID(resp.ID), "ttl": resp.TTL})
+}
+
+// RevokeEtcdLease 撤销lease，绑定的key会被一并删除，query参数：id
+func RevokeEtcdLease(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	leaseID, err := parseEtcdLeaseID(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
+	defer cancel()
+
+	_, err = cli.Revoke(ctx, leaseID)
+	setEtcdEndpointHeader(c, ctx)
+	if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "lease not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// formatEtcdLeaseID lease id格式化为16进制字符串
+func formatEtcdLeaseID(id clientv3.LeaseID) string {
+	return strconv.FormatInt(int64(id), 16)
+}
+
+// parseEtcdLeaseID 解析16进制的lease id
+func parseEtcdLeaseID(s string) (clientv3.LeaseID, error) {
+	id, err := strconv.ParseInt(s, 16, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("id must be a hex lease id")
+	}
+	return clientv3.LeaseID(id), nil
+}
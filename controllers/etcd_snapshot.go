@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DownloadEtcdSnapshot 下载etcd快照文件，仅管理员可用
+func DownloadEtcdSnapshot(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd，下载期间持有客户端引用，避免快照传输中客户端被关闭
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+
+	// 客户端断开时停止传输
+	ctx := withEtcdPeer(c.Request.Context())
+	rc, err := cli.Snapshot(ctx)
+	if err != nil {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer func(rc io.ReadCloser) {
+		_ = rc.Close()
+	}(rc)
+
+	filename := fmt.Sprintf("etcd-%s-%s.db", name, time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.DataFromReader(http.StatusOK, -1, "application/octet-stream", rc, nil)
+}
+
+// StartEtcdSnapshotJob 启动定时备份任务，将开启备份的etcd快照保存到dir/<name>/下，每个etcd保留最近retention个，interval<=0时不启动
+func StartEtcdSnapshotJob(db *gorm.DB, interval time.Duration, dir string, retention int) {
+	if interval <= 0 || dir == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			etcdList, err := model.ListSnapshotEnabledEtcd(db)
+			if err != nil {
+				log.Printf("etcd snapshot: failed to list etcd: %v", err)
+				continue
+			}
+			for _, etcd := range etcdList {
+				if err := saveEtcdSnapshot(etcd, dir, retention); err != nil {
+					log.Printf("etcd snapshot: %s: %v", etcd.Name, err)
+				}
+			}
+		}
+	}()
+}
+
+// saveEtcdSnapshot 保存一次快照并清理超出保留数量的旧快照
+func saveEtcdSnapshot(etcd model.Etcd, dir string, retention int) error {
+	target, err := etcdSnapshotDir(dir, etcd.Name)
+	if err != nil {
+		return err
+	}
+	// 备份期间持有客户端引用，避免快照传输中客户端被关闭
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		return err
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件，完成后再重命名，避免留下不完整的快照
+	filename := filepath.Join(target, fmt.Sprintf("%s-%s.db", etcd.Name, time.Now().Format("20060102150405")))
+	tmp := filename + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	rc, err := cli.Snapshot(ctx)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	_ = rc.Close()
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return err
+	}
+
+	// 清理旧快照
+	if retention <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return err
+	}
+	var snapshots []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".db") {
+			snapshots = append(snapshots, entry.Name())
+		}
+	}
+	// 文件名带时间戳，按名称排序即按时间排序
+	sort.Strings(snapshots)
+	for i := 0; i < len(snapshots)-retention; i++ {
+		if err := os.Remove(filepath.Join(target, snapshots[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// etcdSnapshotDir 返回etcd的快照目录dir/<name>，name含路径分隔符或..时返回错误，避免写入或清理dir以外的文件
+func etcdSnapshotDir(dir string, name string) (string, error) {
+	if name == "" || name == "." || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid etcd name %q for snapshot directory", name)
+	}
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(base, name)
+	if filepath.Dir(target) != base {
+		return "", fmt.Errorf("snapshot directory for %q escapes %s", name, dir)
+	}
+	return target, nil
+}
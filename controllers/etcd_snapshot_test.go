@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEtcdSnapshotDir(t *testing.T) {
+	base, err := filepath.Abs("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "prod-etcd", want: filepath.Join(base, "prod-etcd")},
+		{name: "", wantErr: true},
+		{name: ".", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "../../var/lib/x", wantErr: true},
+		{name: "a/b", wantErr: true},
+		{name: `a\b`, wantErr: true},
+		{name: "a..b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := etcdSnapshotDir("data", tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// 导入模式
+const (
+	etcdImportModeMerge         = "merge"
+	etcdImportModeOverwrite     = "overwrite"
+	etcdImportModeReplacePrefix = "replace-prefix"
+)
+
+// etcdTxnBatchSize 单个事务的最大操作数，etcd默认上限为128
+const etcdTxnBatchSize = 100
+
+// etcdExportDocument 导出文档
+type etcdExportDocument struct {
+	Source     string          `json:"source" yaml:"source"`
+	Prefix     string          `json:"prefix" yaml:"prefix"`
+	Revision   int64           `json:"revision" yaml:"revision"`
+	ExportedAt time.Time       `json:"exportedAt" yaml:"exportedAt"`
+	Keys       []etcdExportKey `json:"keys" yaml:"keys"`
+}
+
+// etcdExportKey 导出的单个key，encoding为base64时value为base64编码
+type etcdExportKey struct {
+	Key      string `json:"key" yaml:"key"`
+	Value    string `json:"value" yaml:"value"`
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+}
+
+// ExportEtcdKeys 导出前缀下的所有key为json或yaml，query参数：prefix、format（json、yaml，默认json）、base64（为true时所有value使用base64，否则只对二进制value编码）
+func ExportEtcdKeys(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	prefix := c.Query("prefix")
+	format := c.DefaultQuery("format", "json")
+	forceBase64 := c.Query("base64") == "true"
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "format must be json or yaml"})
+		return
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 60*time.Second)
+	defer cancel()
+
+	// 在同一revision下分页读取，保证导出一致
+	doc := etcdExportDocument{Source: name, Prefix: prefix, ExportedAt: time.Now(), Keys: []etcdExportKey{}}
+	start := prefix
+	if start == "" {
+		start = "\x00"
+	}
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	for {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(1000),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend)}
+		if doc.Revision > 0 {
+			opts = append(opts, clientv3.WithRev(doc.Revision))
+		}
+		resp, err := cli.Get(ctx, start, opts...)
+		if err != nil {
+			setEtcdEndpointHeader(c, ctx)
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		if doc.Revision == 0 {
+			doc.Revision = resp.Header.Revision
+		}
+		for _, kv := range resp.Kvs {
+			item := etcdExportKey{Key: string(kv.Key), Value: string(kv.Value)}
+			if forceBase64 || !utf8.Valid(kv.Value) {
+				item.Value = base64.StdEncoding.EncodeToString(kv.Value)
+				item.Encoding = "base64"
+			}
+			doc.Keys = append(doc.Keys, item)
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		start = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	setEtcdEndpointHeader(c, ctx)
+
+	filename := fmt.Sprintf("etcd-%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "yaml" {
+		body, err := yaml.Marshal(doc)
+		if err != nil {
+			c.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml; charset=utf-8", body)
+		return
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// ImportEtcdKeys 导入json或yaml文档，表单参数：file、mode（merge、overwrite、replace-prefix，默认merge）、prefix（replace-prefix时清理的前缀，默认为文档中的prefix）
+// merge只新增不存在的key；overwrite新增并覆盖；replace-prefix在overwrite基础上删除前缀下文档中没有的key
+func ImportEtcdKeys(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	mode := c.DefaultPostForm("mode", etcdImportModeMerge)
+	if mode != etcdImportModeMerge && mode != etcdImportModeOverwrite && mode != etcdImportModeReplacePrefix {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "mode must be one of merge, overwrite, replace-prefix"})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "file is required"})
+		return
+	}
+
+	// 解析文档，yaml兼容json
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer func(file io.ReadCloser) {
+		_ = file.Close()
+	}(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	var doc etcdExportDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid document: " + err.Error()})
+		return
+	}
+	values := make(map[string]string, len(doc.Keys))
+	keys := make([]string, 0, len(doc.Keys))
+	for _, item := range doc.Keys {
+		if item.Key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid document: empty key"})
+			return
+		}
+		value := item.Value
+		if item.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(item.Value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid base64 value of %s", item.Key)})
+				return
+			}
+			value = string(decoded)
+		}
+		if _, ok := values[item.Key]; !ok {
+			keys = append(keys, item.Key)
+		}
+		values[item.Key] = value
+	}
+	prefix := c.DefaultPostForm("prefix", doc.Prefix)
+	// 避免误删整个集群
+	if mode == etcdImportModeReplacePrefix && prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "prefix is required in replace-prefix mode"})
+		return
+	}
+
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 60*time.Second)
+	defer cancel()
+
+	summary := map[string]int{"created": 0, "updated": 0, "unchanged": 0, "skipped": 0, "deleted": 0}
+	respond := func(status int, message string) {
+		setEtcdEndpointHeader(c, ctx)
+		c.JSON(status, gin.H{"message": message, "summary": summary})
+	}
+
+	// 分批写入
+	for i := 0; i < len(keys); i += etcdTxnBatchSize {
+		batch := keys[i:min(i+etcdTxnBatchSize, len(keys))]
+
+		// 读取当前值
+		reads := make([]clientv3.Op, 0, len(batch))
+		for _, key := range batch {
+			reads = append(reads, clientv3.OpGet(key))
+		}
+		readResp, err := cli.Txn(ctx).Then(reads...).Commit()
+		if err != nil {
+			respond(500, err.Error())
+			return
+		}
+
+		var cmps []clientv3.Cmp
+		var ops []clientv3.Op
+		created, updated := 0, 0
+		for j, key := range batch {
+			kvs := readResp.Responses[j].GetResponseRange().Kvs
+			switch {
+			case len(kvs) == 0:
+				cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+				ops = append(ops, clientv3.OpPut(key, values[key]))
+				created++
+			case string(kvs[0].Value) == values[key]:
+				summary["unchanged"]++
+			case mode == etcdImportModeMerge:
+				summary["skipped"]++
+			default:
+				cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", kvs[0].ModRevision))
+				ops = append(ops, clientv3.OpPut(key, values[key]))
+				updated++
+			}
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		txnResp, err := cli.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			respond(500, err.Error())
+			return
+		}
+		if !txnResp.Succeeded {
+			respond(http.StatusConflict, fmt.Sprintf("keys were modified concurrently in batch starting at %s", batch[0]))
+			return
+		}
+		summary["created"] += created
+		summary["updated"] += updated
+	}
+
+	// 删除前缀下文档中没有的key
+	if mode == etcdImportModeReplacePrefix {
+		resp, err := cli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+		if err != nil {
+			respond(500, err.Error())
+			return
+		}
+		var stale []string
+		for _, kv := range resp.Kvs {
+			if _, ok := values[string(kv.Key)]; !ok {
+				stale = append(stale, string(kv.Key))
+			}
+		}
+		for i := 0; i < len(stale); i += etcdTxnBatchSize {
+			var ops []clientv3.Op
+			for _, key := range stale[i:min(i+etcdTxnBatchSize, len(stale))] {
+				ops = append(ops, clientv3.OpDelete(key))
+			}
+			if _, err := cli.Txn(ctx).Then(ops...).Commit(); err != nil {
+				respond(500, err.Error())
+				return
+			}
+			summary["deleted"] += len(ops)
+		}
+	}
+
+	respond(http.StatusOK, "true")
+}
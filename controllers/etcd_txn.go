@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdTxnMaxOps 单个事务允许的最大操作数
+const etcdTxnMaxOps = 128
+
+// EtcdTxnRequest 多key事务请求
+type EtcdTxnRequest struct {
+	Operations []EtcdTxnOperation `json:"operations" binding:"required"`
+}
+
+// EtcdTxnOperation 事务中的单个操作，type为put或delete，compare为可选的前置条件
+type EtcdTxnOperation struct {
+	Type    string          `json:"type" binding:"required"`
+	Key     string          `json:"key" binding:"required"`
+	Value   string          `json:"value"`
+	Compare *EtcdTxnCompare `json:"compare"`
+}
+
+// EtcdTxnCompare 前置条件，target为value、version、mod_revision、create_revision，result为=、!=、>、<
+// target为value时比较value字段，否则比较revision字段
+type EtcdTxnCompare struct {
+	Target   string `json:"target" binding:"required"`
+	Result   string `json:"result"`
+	Value    string `json:"value"`
+	Revision int64  `json:"revision"`
+}
+
+// etcdTxnResult 单个操作的执行结果
+type etcdTxnResult struct {
+	Type        string `json:"type"`
+	Key         string `json:"key"`
+	ModRevision int64  `json:"mod_revision,omitempty"`
+	Deleted     int64  `json:"deleted,omitempty"`
+}
+
+// etcdTxnFailure 未满足的前置条件及key的当前状态
+type etcdTxnFailure struct {
+	Key     string         `json:"key"`
+	Compare EtcdTxnCompare `json:"compare"`
+	Exists  bool           `json:"exists"`
+	Current gin.H          `json:"current,omitempty"`
+}
+
+// ExecEtcdTxn 在一个etcd事务中原子执行多个put、delete操作，json参数：operations
+// 所有前置条件满足时全部执行并返回每个操作的结果，否则不执行任何操作并返回409和未满足的条件
+func ExecEtcdTxn(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	var req EtcdTxnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > etcdTxnMaxOps {
+		c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("operations must contain 1 to %d items", etcdTxnMaxOps)})
+		return
+	}
+
+	// 构造条件和操作
+	var cmps []clientv3.Cmp
+	var compared []EtcdTxnOperation
+	ops := make([]clientv3.Op, 0, len(req.Operations))
+	seen := make(map[string]bool)
+	for i, op := range req.Operations {
+		// etcd事务中同一个key不能被修改多次
+		if seen[op.Key] {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("operations[%d]: duplicate key %s", i, op.Key)})
+			return
+		}
+		seen[op.Key] = true
+		switch op.Type {
+		case "put":
+			ops = append(ops, clientv3.OpPut(op.Key, op.Value))
+		case "delete":
+			ops = append(ops, clientv3.OpDelete(op.Key))
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("operations[%d]: type must be put or delete", i)})
+			return
+		}
+		if op.Compare == nil {
+			continue
+		}
+		if op.Compare.Result == "" {
+			op.Compare.Result = "="
+		}
+		cmp, err := buildEtcdCompare(op.Key, *op.Compare)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("operations[%d]: %v", i, err)})
+			return
+		}
+		cmps = append(cmps, cmp)
+		compared = append(compared, op)
+	}
+
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
+	defer cancel()
+
+	// 条件不满足时读取被比较的key，用于返回失败原因
+	elseOps := make([]clientv3.Op, 0, len(compared))
+	for _, op := range compared {
+		elseOps = append(elseOps, clientv3.OpGet(op.Key))
+	}
+	resp, err := cli.Txn(ctx).If(cmps...).Then(ops...).Else(elseOps...).Commit()
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !resp.Succeeded {
+		failures := make([]etcdTxnFailure, 0)
+		for i, op := range compared {
+			var kv *mvccpb.KeyValue
+			if kvs := resp.Responses[i].GetResponseRange().Kvs; len(kvs) > 0 {
+				kv = kvs[0]
+			}
+			if etcdCompareHolds(kv, *op.Compare) {
+				continue
+			}
+			failure := etcdTxnFailure{Key: op.Key, Compare: *op.Compare, Exists: kv != nil}
+			if kv != nil {
+				failure.Current = gin.H{
+					"value":           string(kv.Value),
+					"version":         kv.Version,
+					"mod_revision":    kv.ModRevision,
+					"create_revision": kv.CreateRevision,
+				}
+			}
+			failures = append(failures, failure)
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  "transaction conditions not met, nothing was applied",
+			"failures": failures,
+		})
+		return
+	}
+
+	results := make([]etcdTxnResult, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		result := etcdTxnResult{Type: op.Type, Key: op.Key}
+		if op.Type == "put" {
+			result.ModRevision = resp.Header.Revision
+		} else if r := resp.Responses[i].GetResponseDeleteRange(); r != nil {
+			result.Deleted = r.Deleted
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "true",
+		"revision": resp.Header.Revision,
+		"results":  results,
+	})
+}
+
+// buildEtcdCompare 根据前置条件构造etcd Compare
+func buildEtcdCompare(key string, cmp EtcdTxnCompare) (clientv3.Cmp, error) {
+	switch cmp.Result {
+	case "=", "!=", ">", "<":
+	default:
+		return clientv3.Cmp{}, fmt.Errorf("compare result must be one of =, !=, >, <")
+	}
+	switch cmp.Target {
+	case "value":
+		return clientv3.Compare(clientv3.Value(key), cmp.Result, cmp.Value), nil
+	case "version":
+		return clientv3.Compare(clientv3.Version(key), cmp.Result, cmp.Revision), nil
+	case "mod_revision":
+		return clientv3.Compare(clientv3.ModRevision(key), cmp.Result, cmp.Revision), nil
+	case "create_revision":
+		return clientv3.Compare(clientv3.CreateRevision(key), cmp.Result, cmp.Revision), nil
+	default:
+		return clientv3.Cmp{}, fmt.Errorf("compare target must be one of value, version, mod_revision, create_revision")
+	}
+}
+
+// etcdCompareHolds 根据key的当前状态判断前置条件是否成立，kv为nil表示key不存在
+func etcdCompareHolds(kv *mvccpb.KeyValue, cmp EtcdTxnCompare) bool {
+	var sign int
+	switch cmp.Target {
+	case "value":
+		// key不存在时value比较恒为false
+		if kv == nil {
+			return false
+		}
+		current := string(kv.Value)
+		switch {
+		case current < cmp.Value:
+			sign = -1
+		case current > cmp.Value:
+			sign = 1
+		}
+	default:
+		var current int64
+		if kv != nil {
+			switch cmp.Target {
+			case "version":
+				current = kv.Version
+			case "mod_revision":
+				current = kv.ModRevision
+			case "create_revision":
+				current = kv.CreateRevision
+			}
+		}
+		switch {
+		case current < cmp.Revision:
+			sign = -1
+		case current > cmp.Revision:
+			sign = 1
+		}
+	}
+	switch cmp.Result {
+	case "=":
+		return sign == 0
+	case "!=":
+		return sign != 0
+	case ">":
+		return sign > 0
+	default:
+		return sign < 0
+	}
+}
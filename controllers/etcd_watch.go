@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// etcdWatchEvent 推送给浏览器的watch事件
+type etcdWatchEvent struct {
+	Type           string `json:"type"`
+	Key            string `json:"key"`
+	Value          string `json:"value"`
+	PrevValue      string `json:"prev_value,omitempty"`
+	ModRevision    int64  `json:"mod_revision"`
+	CreateRevision int64  `json:"create_revision"`
+	Version        int64  `json:"version"`
+}
+
+// WatchEtcdKey 以SSE方式推送key或前缀的变更事件，直到客户端断开，watch中断时推送error事件，query参数：key、prefix（为true时监听前缀）、revision（从该revision开始）
+func WatchEtcdKey(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	key := c.Query("key")
+	prefix := c.Query("prefix") == "true"
+	if key == "" && !prefix {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "key is required"})
+		return
+	}
+	opts := []clientv3.OpOption{clientv3.WithPrevKV()}
+	if prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	if s := c.Query("revision"); s != "" {
+		revision, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || revision <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "revision must be a positive integer"})
+			return
+		}
+		opts = append(opts, clientv3.WithRev(revision))
+	}
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	// 连接到etcd，watch期间持有客户端引用，避免被空闲淘汰或配置更新关闭
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	defer release()
+
+	// 客户端断开时取消watch
+	ctx := clientv3.WithRequireLeader(c.Request.Context())
+	watchChan := cli.Watch(ctx, key, opts...)
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case resp, ok := <-watchChan:
+			// watch被服务端或客户端关闭时通知浏览器
+			if !ok {
+				c.SSEvent("error", gin.H{"message": "watch closed"})
+				return false
+			}
+			if err := resp.Err(); err != nil {
+				c.SSEvent("error", gin.H{"message": err.Error()})
+				return false
+			}
+			for _, ev := range resp.Events {
+				event := etcdWatchEvent{
+					Type:           ev.Type.String(),
+					Key:            string(ev.Kv.Key),
+					Value:          string(ev.Kv.Value),
+					ModRevision:    ev.Kv.ModRevision,
+					CreateRevision: ev.Kv.CreateRevision,
+					Version:        ev.Kv.Version,
+				}
+				if ev.PrevKv != nil {
+					event.PrevValue = string(ev.PrevKv.Value)
+				}
+				c.SSEvent("event", event)
+			}
+			return true
+		}
+	})
+}
@@ -3,6 +3,9 @@ package controllers
 import (
 	"codepub-service/crypt"
 	"codepub-service/model"
+	"codepub-service/sqlreview"
+	"codepub-service/sqlscan"
+	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/mysql"
@@ -10,14 +13,69 @@ import (
 	"net/http"
 )
 
+// ExecMysqlSql 执行sql，表单参数：sql、confirm（为true时确认执行有warning的sql）
+// 执行前按实例规则审核，有error时返回400，有warning且未确认时返回409
+// 查询语句返回columns、rows和truncated，写语句返回rows_affected和last_insert_id
 func ExecMysqlSql(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
-	sql := c.PostForm("sql")
+	query := c.PostForm("sql")
+	conn, ok := openMysqlConn(c, db, name)
+	if !ok {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// 执行前审核
+	review, err := reviewSql(db, sqlreview.MySQL, name, query, mysqlTableRows(c.Request.Context(), conn))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if sqlReviewBlocked(c, review, c.PostForm("confirm") == "true") {
+		return
+	}
+
+	// 查询语句返回结果集，写语句返回影响行数
+	result, err := execSql(c.Request.Context(), conn, sqlscan.MySQL, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(review.Issues) > 0 {
+		result["review"] = review
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ReviewMysqlSql 只审核sql不执行，表单参数：sql
+func ReviewMysqlSql(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	query := c.PostForm("sql")
+	conn, ok := openMysqlConn(c, db, name)
+	if !ok {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	review, err := reviewSql(db, sqlreview.MySQL, name, query, mysqlTableRows(c.Request.Context(), conn))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// openMysqlConn 连接到mysql实例，失败时已写入响应
+func openMysqlConn(c *gin.Context, db *gorm.DB, name string) (*sql.DB, bool) {
 	// 获取 Mysql URL、Username、Password
 	Mysql, err := model.GetMysqlUrlByName(db, name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-		return
+		return nil, false
 	}
 
 	// password解密
@@ -26,7 +84,7 @@ func ExecMysqlSql(c *gin.Context, db *gorm.DB) {
 		decryptedPassword, err := crypt.Decrypt(encryptionKey, Mysql.Password)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt password"})
-			return
+			return nil, false
 		}
 		Mysql.Password = decryptedPassword
 	}
@@ -37,12 +95,12 @@ func ExecMysqlSql(c *gin.Context, db *gorm.DB) {
 	conn, err := gorm.Open(mysql.Open(connStr), &gorm.Config{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-		return
+		return nil, false
 	}
-	result := conn.Exec(sql)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
-		return
+	sqlDB, err := conn.DB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return nil, false
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "true"})
+	return sqlDB, true
 }
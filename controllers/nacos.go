@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"codepub-service/model"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GetNacosAllNamespace 通过name获取对应nacos url下的所有namespace列表
@@ -40,11 +44,36 @@ func GetNacosAllNamespace(c *gin.Context, db *gorm.DB) {
 	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
 }
 
-// GetNacosAllConfig 通过name获取对应nacos url下的所有config列表，query参数：pageSize、tenant
+// GetNacosAllConfig 通过name分页获取对应nacos url下的config列表
+// query参数：tenant、pageNo、pageSize、dataId、group、appName、tags、search（accurate、blur，默认accurate）
 func GetNacosAllConfig(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
-	pageSize, _ := strconv.Atoi(c.Query("pageSize"))
-	tenant := c.Query("tenant")
+	pageNo, err := strconv.Atoi(c.DefaultQuery("pageNo", "1"))
+	if err != nil || pageNo < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "pageNo must be a positive integer"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 || pageSize > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "pageSize must be between 1 and 500"})
+		return
+	}
+	search := c.DefaultQuery("search", "accurate")
+	if search != "accurate" && search != "blur" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "search must be accurate or blur"})
+		return
+	}
+	dataId := c.Query("dataId")
+	group := c.Query("group")
+	// 模糊查询时nacos使用*作为通配符，未指定通配符时按包含匹配
+	if search == "blur" {
+		if dataId != "" && !strings.Contains(dataId, "*") {
+			dataId = "*" + dataId + "*"
+		}
+		if group != "" && !strings.Contains(group, "*") {
+			group = "*" + group + "*"
+		}
+	}
 	// 获取nacos url
 	nacosUrl, err := model.GetNacosUrlByName(db, name)
 	if err != nil {
@@ -54,23 +83,68 @@ func GetNacosAllConfig(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	nacosUrl = fmt.Sprintf("%s/v1/cs/configs?dataId=&group=&appName=&config_tags=&pageNo=1&pageSize=%d&tenant=%s&search=accurate", nacosUrl, pageSize, tenant)
-
 	// 获取config列表
-	resp, err := http.Get(nacosUrl)
+	page, err := queryNacosConfigPage(nacosUrl, url.Values{
+		"dataId":      {dataId},
+		"group":       {group},
+		"appName":     {c.Query("appName")},
+		"config_tags": {c.Query("tags")},
+		"pageNo":      {strconv.Itoa(pageNo)},
+		"pageSize":    {strconv.Itoa(pageSize)},
+		"tenant":      {c.Query("tenant")},
+		"search":      {search},
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Failed to fetch namespaces: " + err.Error(),
+			"message": "Failed to fetch configs: " + err.Error(),
 		})
 		return
 	}
 
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
-	// 处理响应
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	items := page.PageItems
+	if items == nil {
+		items = []nacosConfigItem{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":    items,
+		"total":    page.TotalCount,
+		"page":     pageNo,
+		"pageSize": pageSize,
+		"pages":    page.PagesAvailable,
+	})
+}
+
+// GetNacosConfig 获取单个nacos配置的内容和元数据，query参数：tenant、dataId、group
+func GetNacosConfig(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	tenant := c.Query("tenant")
+	dataId := c.Query("dataId")
+	group := c.Query("group")
+	if dataId == "" || group == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "dataId and group are required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	detail, err := getNacosConfigDetail(nacosUrl, tenant, dataId, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "config not found"})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
 }
 
 // SaveNacosConfig 新增或修改nacos配置，表单参数：tenant、dataId、group、content、type
@@ -134,7 +208,7 @@ func SaveNacosConfig(c *gin.Context, db *gorm.DB) {
 	c.JSON(http.StatusOK, gin.H{"message": "true"})
 }
 
-// DeleteNacosConfig 删除nacos配置，query参数：tenant、dataId、group
+// DeleteNacosConfig 删除nacos配置，query参数：tenant、dataId、group，删除前的配置保存到回收站
 func DeleteNacosConfig(c *gin.Context, db *gorm.DB) {
 	name := c.Param("name")
 	tenant := c.Query("tenant")
@@ -149,10 +223,230 @@ func DeleteNacosConfig(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	// 删除前放入回收站
+	detail, err := getNacosConfigDetail(nacosUrl, tenant, dataId, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	var trash *model.Trash
+	var trashErr error
+	if detail != nil {
+		trash = &model.Trash{
+			Kind:      model.TrashKindNacos,
+			Instance:  name,
+			Tenant:    tenant,
+			Group:     group,
+			DataId:    dataId,
+			Type:      detail.Type,
+			AppName:   detail.AppName,
+			Value:     detail.Content,
+			DeletedBy: model.CurrentUserID(c),
+		}
+		// 回收站写入失败时不影响删除
+		if trashErr = db.Create(trash).Error; trashErr != nil {
+			log.Printf("nacos %s: failed to save trash for %s/%s/%s: %v", name, tenant, group, dataId, trashErr)
+			trash = nil
+		}
+	}
+	// 删除失败时移除回收站条目
+	removeTrash := func() {
+		if trash != nil {
+			db.Delete(trash)
+		}
+	}
+
 	nacosUrl = fmt.Sprintf("%s/v1/cs/configs?dataId=%s&group=%s&tenant=%s", nacosUrl, dataId, group, tenant)
 
 	// 创建 DELETE 请求
 	req, err := http.NewRequest("DELETE", nacosUrl, nil)
+	if err != nil {
+		removeTrash()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 发送请求
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		removeTrash()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+	// 处理响应
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "true" {
+		removeTrash()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	if trash != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "true", "trash": trash.ID})
+		return
+	}
+	if trashErr != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "true", "trash_error": trashErr.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// CreateNacosNamespace 新增nacos namespace，表单参数：namespaceId（可选）、namespaceName、namespaceDesc
+func CreateNacosNamespace(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	namespaceId := c.PostForm("namespaceId")
+	namespaceName := c.PostForm("namespaceName")
+	namespaceDesc := c.PostForm("namespaceDesc")
+	if namespaceName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "namespaceName is required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 发送 POST 请求
+	formData := url.Values{
+		"customNamespaceId": {namespaceId},
+		"namespaceName":     {namespaceName},
+		"namespaceDesc":     {namespaceDesc},
+	}
+	resp, err := http.Post(nacosUrl+"/v1/console/namespaces", "application/x-www-form-urlencoded", strings.NewReader(formData.Encode()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// 处理响应
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "true" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// UpdateNacosNamespace 修改nacos namespace的名称和描述，表单参数：namespaceId、namespaceName、namespaceDesc
+func UpdateNacosNamespace(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	namespaceId := c.PostForm("namespaceId")
+	namespaceName := c.PostForm("namespaceName")
+	namespaceDesc := c.PostForm("namespaceDesc")
+	if namespaceId == "" || namespaceName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "namespaceId and namespaceName are required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 创建 PUT 请求
+	formData := url.Values{
+		"namespace":         {namespaceId},
+		"namespaceShowName": {namespaceName},
+		"namespaceDesc":     {namespaceDesc},
+	}
+	req, err := http.NewRequest("PUT", nacosUrl+"/v1/console/namespaces", strings.NewReader(formData.Encode()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// 发送请求
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// 处理响应
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "true" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// DeleteNacosNamespace 删除nacos namespace，query参数：namespaceId、force
+// namespace下仍有配置时拒绝删除，除非force=true
+func DeleteNacosNamespace(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	namespaceId := c.Query("namespaceId")
+	force := c.Query("force") == "true"
+	if namespaceId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "namespaceId is required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 安全检查：namespace下仍有配置时不允许删除
+	if !force {
+		count, err := countNacosConfig(nacosUrl, namespaceId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Failed to count configs: " + err.Error(),
+			})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"message":    fmt.Sprintf("namespace still contains %d configs, use force=true to delete", count),
+				"totalCount": count,
+			})
+			return
+		}
+	}
+
+	// 创建 DELETE 请求
+	req, err := http.NewRequest("DELETE", nacosUrl+"/v1/console/namespaces?namespaceId="+url.QueryEscape(namespaceId), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -172,6 +466,7 @@ func DeleteNacosConfig(c *gin.Context, db *gorm.DB) {
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
+
 	// 处理响应
 	body, _ := io.ReadAll(resp.Body)
 	if string(body) != "true" {
@@ -182,3 +477,180 @@ func DeleteNacosConfig(c *gin.Context, db *gorm.DB) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "true"})
 }
+
+// countNacosConfig 获取namespace下的配置数量
+func countNacosConfig(nacosUrl string, tenant string) (int, error) {
+	page, err := queryNacosConfigPage(nacosUrl, url.Values{
+		"dataId":   {""},
+		"group":    {""},
+		"pageNo":   {"1"},
+		"pageSize": {"1"},
+		"tenant":   {tenant},
+		"search":   {"accurate"},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return page.TotalCount, nil
+}
+
+// nacosConfigItem nacos配置项
+type nacosConfigItem struct {
+	DataId  string `json:"dataId"`
+	Group   string `json:"group"`
+	Content string `json:"content"`
+	Md5     string `json:"md5"`
+	Tenant  string `json:"tenant"`
+	AppName string `json:"appName"`
+	Type    string `json:"type"`
+}
+
+// nacosConfigPage nacos配置列表分页响应
+type nacosConfigPage struct {
+	TotalCount     int               `json:"totalCount"`
+	PageNumber     int               `json:"pageNumber"`
+	PagesAvailable int               `json:"pagesAvailable"`
+	PageItems      []nacosConfigItem `json:"pageItems"`
+}
+
+// queryNacosConfigPage 查询一页nacos配置
+func queryNacosConfigPage(nacosUrl string, params url.Values) (nacosConfigPage, error) {
+	var page nacosConfigPage
+	resp, err := http.Get(nacosUrl + "/v1/cs/configs?" + params.Encode())
+	if err != nil {
+		return page, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return page, errors.New(string(body))
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+// listAllNacosConfig 分页拉取namespace下的全部配置（含content），group、dataId为空时不过滤
+func listAllNacosConfig(nacosUrl string, tenant string, group string, dataId string) ([]nacosConfigItem, error) {
+	var items []nacosConfigItem
+	for pageNo := 1; ; pageNo++ {
+		page, err := queryNacosConfigPage(nacosUrl, url.Values{
+			"dataId":   {dataId},
+			"group":    {group},
+			"pageNo":   {strconv.Itoa(pageNo)},
+			"pageSize": {"100"},
+			"tenant":   {tenant},
+			"search":   {"accurate"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.PageItems...)
+		if len(page.PageItems) == 0 || pageNo >= page.PagesAvailable {
+			break
+		}
+	}
+	return items, nil
+}
+
+// nacosConfigDetail 单个nacos配置的详情
+type nacosConfigDetail struct {
+	DataId       string    `json:"dataId"`
+	Group        string    `json:"group"`
+	Tenant       string    `json:"tenant"`
+	Content      string    `json:"content"`
+	Type         string    `json:"type"`
+	Md5          string    `json:"md5"`
+	AppName      string    `json:"appName"`
+	Desc         string    `json:"desc"`
+	Tags         string    `json:"tags"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// getNacosConfigDetail 获取单个配置详情，配置不存在时返回nil
+func getNacosConfigDetail(nacosUrl string, tenant string, dataId string, group string) (*nacosConfigDetail, error) {
+	params := url.Values{
+		"dataId": {dataId},
+		"group":  {group},
+		"tenant": {tenant},
+		"show":   {"all"},
+	}
+	resp, err := http.Get(nacosUrl + "/v1/cs/configs?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	// show=all时配置不存在会返回空
+	if len(strings.TrimSpace(string(body))) == 0 || strings.TrimSpace(string(body)) == "null" {
+		return nil, nil
+	}
+
+	var raw struct {
+		DataId     string `json:"dataId"`
+		Group      string `json:"group"`
+		Tenant     string `json:"tenant"`
+		Content    string `json:"content"`
+		Type       string `json:"type"`
+		Md5        string `json:"md5"`
+		AppName    string `json:"appName"`
+		Desc       string `json:"desc"`
+		ConfigTags string `json:"configTags"`
+		ModifyTime int64  `json:"modifyTime"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	detail := &nacosConfigDetail{
+		DataId:  raw.DataId,
+		Group:   raw.Group,
+		Tenant:  raw.Tenant,
+		Content: raw.Content,
+		Type:    raw.Type,
+		Md5:     raw.Md5,
+		AppName: raw.AppName,
+		Desc:    raw.Desc,
+		Tags:    raw.ConfigTags,
+	}
+	if raw.ModifyTime > 0 {
+		detail.LastModified = time.UnixMilli(raw.ModifyTime)
+	}
+	return detail, nil
+}
+
+// publishNacosConfig 发布配置到指定nacos
+func publishNacosConfig(nacosUrl string, tenant string, item nacosConfigItem) error {
+	formData := url.Values{
+		"dataId":  {item.DataId},
+		"group":   {item.Group},
+		"content": {item.Content},
+		"type":    {item.Type},
+		"appName": {item.AppName},
+		"tenant":  {tenant},
+	}
+	resp, err := http.Post(nacosUrl+"/v1/cs/configs", "application/x-www-form-urlencoded", strings.NewReader(formData.Encode()))
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "true" {
+		return errors.New(string(body))
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"codepub-service/model"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// nacosArchiveMetaFile 归档中的元数据文件名
+const nacosArchiveMetaFile = "metadata.json"
+
+// nacosArchiveMeta 归档元数据
+type nacosArchiveMeta struct {
+	Source     string              `json:"source"`
+	Tenant     string              `json:"tenant"`
+	ExportedAt time.Time           `json:"exportedAt"`
+	Items      []nacosArchiveEntry `json:"items"`
+}
+
+// nacosArchiveEntry 归档中单个配置的元数据
+type nacosArchiveEntry struct {
+	DataId  string `json:"dataId"`
+	Group   string `json:"group"`
+	Type    string `json:"type"`
+	AppName string `json:"appName"`
+	Md5     string `json:"md5"`
+}
+
+// ExportNacosConfig 导出namespace下的配置为归档文件，query参数：tenant、group、dataId、format（zip、tar，默认zip）
+// 归档内按group/dataId存放配置内容，并附带metadata.json
+func ExportNacosConfig(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	tenant := c.Query("tenant")
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "tar" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "format must be zip or tar"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 拉取配置
+	items, err := listAllNacosConfig(nacosUrl, tenant, c.Query("group"), c.Query("dataId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch configs: " + err.Error(),
+		})
+		return
+	}
+
+	// 生成归档
+	buf, err := buildNacosArchive(format, name, tenant, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to build archive: " + err.Error(),
+		})
+		return
+	}
+
+	namespace := tenant
+	if namespace == "" {
+		namespace = "public"
+	}
+	filename := fmt.Sprintf("nacos-%s-%s-%s.%s", name, namespace, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	contentType := "application/zip"
+	if format == "tar" {
+		contentType = "application/x-tar"
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportNacosConfig 导入归档文件到nacos，表单参数：file、tenant、policy（skip、overwrite，默认skip）
+func ImportNacosConfig(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	tenant := c.PostForm("tenant")
+	policy := c.DefaultPostForm("policy", nacosSyncPolicySkip)
+	if policy != nacosSyncPolicySkip && policy != nacosSyncPolicyOverwrite {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "policy must be skip or overwrite"})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "file is required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 读取归档
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer func(file io.ReadCloser) {
+		_ = file.Close()
+	}(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	items, err := parseNacosArchive(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid archive: " + err.Error()})
+		return
+	}
+
+	// 目标已有配置
+	targetItems, err := listAllNacosConfig(nacosUrl, tenant, "", "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch target configs: " + err.Error()})
+		return
+	}
+	existing := make(map[string]nacosConfigItem, len(targetItems))
+	for _, item := range targetItems {
+		existing[item.Group+"/"+item.DataId] = item
+	}
+
+	// 导入
+	result := make([]nacosSyncPlanItem, 0, len(items))
+	summary := map[string]int{"create": 0, "update": 0, "unchanged": 0, "skip": 0, "failed": 0}
+	for _, item := range items {
+		action := "create"
+		if old, ok := existing[item.Group+"/"+item.DataId]; ok {
+			switch {
+			case old.Content == item.Content:
+				action = "unchanged"
+			case policy == nacosSyncPolicySkip:
+				action = "skip"
+			default:
+				action = "update"
+			}
+		}
+		planItem := nacosSyncPlanItem{DataId: item.DataId, Group: item.Group, Action: action}
+		if action == "create" || action == "update" {
+			if err := publishNacosConfig(nacosUrl, tenant, item); err != nil {
+				planItem.Error = err.Error()
+				summary["failed"]++
+			}
+		}
+		summary[action]++
+		result = append(result, planItem)
+	}
+	status := http.StatusOK
+	if summary["failed"] > 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{"summary": summary, "items": result})
+}
+
+// buildNacosArchive 将配置打包为zip或tar
+func buildNacosArchive(format string, source string, tenant string, items []nacosConfigItem) (*bytes.Buffer, error) {
+	meta := nacosArchiveMeta{Source: source, Tenant: tenant, ExportedAt: time.Now()}
+	for _, item := range items {
+		meta.Items = append(meta.Items, nacosArchiveEntry{
+			DataId:  item.DataId,
+			Group:   item.Group,
+			Type:    item.Type,
+			AppName: item.AppName,
+			Md5:     item.Md5,
+		})
+	}
+	metaBytes, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if format == "tar" {
+		tw := tar.NewWriter(buf)
+		writeFile := func(name string, content []byte) error {
+			hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: meta.ExportedAt}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := tw.Write(content)
+			return err
+		}
+		for _, item := range items {
+			if err := writeFile(path.Join(item.Group, item.DataId), []byte(item.Content)); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeFile(nacosArchiveMetaFile, metaBytes); err != nil {
+			return nil, err
+		}
+		return buf, tw.Close()
+	}
+
+	zw := zip.NewWriter(buf)
+	writeFile := func(name string, content []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+	for _, item := range items {
+		if err := writeFile(path.Join(item.Group, item.DataId), []byte(item.Content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeFile(nacosArchiveMetaFile, metaBytes); err != nil {
+		return nil, err
+	}
+	return buf, zw.Close()
+}
+
+// parseNacosArchive 解析zip或tar归档，返回其中的配置
+func parseNacosArchive(data []byte) ([]nacosConfigItem, error) {
+	files := make(map[string][]byte)
+	if bytes.HasPrefix(data, []byte("PK")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[f.Name] = content
+		}
+	} else {
+		tr := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			files[hdr.Name] = content
+		}
+	}
+
+	// 读取元数据，用于还原type、appName
+	entries := make(map[string]nacosArchiveEntry)
+	if metaBytes, ok := files[nacosArchiveMetaFile]; ok {
+		var meta nacosArchiveMeta
+		if err := json.Unmarshal(metaBytes, &meta); err != nil {
+			return nil, fmt.Errorf("%s: %v", nacosArchiveMetaFile, err)
+		}
+		for _, entry := range meta.Items {
+			entries[path.Join(entry.Group, entry.DataId)] = entry
+		}
+		delete(files, nacosArchiveMetaFile)
+	}
+
+	var items []nacosConfigItem
+	for name, content := range files {
+		name = strings.TrimPrefix(path.Clean(name), "/")
+		group, dataId := path.Split(name)
+		group = strings.TrimSuffix(group, "/")
+		if group == "" || dataId == "" || strings.Contains(group, "/") {
+			return nil, fmt.Errorf("unexpected file %q, want group/dataId", name)
+		}
+		item := nacosConfigItem{DataId: dataId, Group: group, Content: string(content), Type: "text"}
+		if entry, ok := entries[name]; ok {
+			item.Type = entry.Type
+			item.AppName = entry.AppName
+		}
+		items = append(items, item)
+	}
+	// 文件按map遍历，排序后保证导入顺序和返回结果稳定
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Group != items[j].Group {
+			return items[i].Group < items[j].Group
+		}
+		return items[i].DataId < items[j].DataId
+	})
+	return items, nil
+}
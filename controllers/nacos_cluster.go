@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// nacosClusterMember nacos集群节点
+type nacosClusterMember struct {
+	Address         string                    `json:"address"`
+	State           string                    `json:"state"`
+	Version         string                    `json:"version"`
+	LastRefreshTime int64                     `json:"lastRefreshTime"`
+	FailAccessCnt   int                       `json:"failAccessCnt"`
+	Raft            map[string]nacosRaftGroup `json:"raft"`
+}
+
+// nacosRaftGroup 节点上报的raft分组信息
+type nacosRaftGroup struct {
+	Leader  string   `json:"leader"`
+	Term    int64    `json:"term"`
+	Members []string `json:"members"`
+}
+
+// GetNacosCluster 通过name获取对应nacos的集群节点、状态、raft leader和版本信息
+func GetNacosCluster(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 服务端状态
+	var serverState map[string]interface{}
+	if err := getNacosJson(nacosUrl+"/v1/console/server/state", &serverState); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch server state: " + err.Error(),
+		})
+		return
+	}
+
+	// 集群节点
+	var nodes struct {
+		Data []struct {
+			Address       string `json:"address"`
+			State         string `json:"state"`
+			FailAccessCnt int    `json:"failAccessCnt"`
+			ExtendInfo    struct {
+				Version         string `json:"version"`
+				LastRefreshTime int64  `json:"lastRefreshTime"`
+				RaftMetaData    struct {
+					MetaDataMap map[string]struct {
+						Leader          string   `json:"leader"`
+						Term            int64    `json:"term"`
+						RaftGroupMember []string `json:"raftGroupMember"`
+					} `json:"metaDataMap"`
+				} `json:"raftMetaData"`
+			} `json:"extendInfo"`
+		} `json:"data"`
+	}
+	if err := getNacosJson(nacosUrl+"/v1/core/cluster/nodes?withInstances=false&pageNo=1&pageSize=1000", &nodes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch cluster nodes: " + err.Error(),
+		})
+		return
+	}
+
+	members := make([]nacosClusterMember, 0, len(nodes.Data))
+	healthy := 0
+	for _, node := range nodes.Data {
+		member := nacosClusterMember{
+			Address:         node.Address,
+			State:           node.State,
+			Version:         node.ExtendInfo.Version,
+			LastRefreshTime: node.ExtendInfo.LastRefreshTime,
+			FailAccessCnt:   node.FailAccessCnt,
+			Raft:            make(map[string]nacosRaftGroup),
+		}
+		for group, meta := range node.ExtendInfo.RaftMetaData.MetaDataMap {
+			member.Raft[group] = nacosRaftGroup{Leader: meta.Leader, Term: meta.Term, Members: meta.RaftGroupMember}
+		}
+		if node.State == "UP" {
+			healthy++
+		}
+		members = append(members, member)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"server":  serverState,
+		"healthy": len(members) > 0 && healthy == len(members),
+		"total":   len(members),
+		"up":      healthy,
+		"members": members,
+	})
+}
+
+// getNacosJson 调用nacos的GET接口并解析json响应
+func getNacosJson(apiUrl string, v interface{}) error {
+	resp, err := http.Get(apiUrl)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(string(body))
+	}
+	return json.Unmarshal(body, v)
+}
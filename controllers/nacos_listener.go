@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// nacosListenerStatus 单个监听项，stale为true表示客户端持有的md5与当前发布版本不一致
+type nacosListenerStatus struct {
+	Ip     string `json:"ip,omitempty"`
+	DataId string `json:"dataId,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	Md5    string `json:"md5"`
+	Stale  bool   `json:"stale"`
+	Error  string `json:"error,omitempty"`
+}
+
+// nacosListenerConcurrency 比较客户端监听配置时并发查询配置详情的数量
+const nacosListenerConcurrency = 8
+
+// GetNacosConfigListener 获取监听某个配置的客户端及其md5，query参数：tenant、dataId、group
+func GetNacosConfigListener(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	tenant := c.Query("tenant")
+	dataId := c.Query("dataId")
+	group := c.Query("group")
+	if dataId == "" || group == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "dataId and group are required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 当前发布版本
+	detail, err := getNacosConfigDetail(nacosUrl, tenant, dataId, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	currentMd5 := ""
+	if detail != nil {
+		currentMd5 = detail.Md5
+	}
+
+	// 查询监听者
+	status, err := queryNacosListener(nacosUrl+"/v1/cs/configs/listener", url.Values{
+		"dataId":     {dataId},
+		"group":      {group},
+		"tenant":     {tenant},
+		"sampleTime": {"1"},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch listeners: " + err.Error(),
+		})
+		return
+	}
+
+	listeners := make([]nacosListenerStatus, 0, len(status))
+	staleCount := 0
+	for ip, md5 := range status {
+		stale := md5 != currentMd5
+		if stale {
+			staleCount++
+		}
+		listeners = append(listeners, nacosListenerStatus{Ip: ip, Md5: md5, Stale: stale})
+	}
+	sort.Slice(listeners, func(i, j int) bool {
+		return listeners[i].Ip < listeners[j].Ip
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"md5":        currentMd5,
+		"staleCount": staleCount,
+		"listeners":  listeners,
+	})
+}
+
+// GetNacosClientListener 获取某个客户端ip监听的所有配置及其md5，query参数：ip、tenant
+// 无法获取当前发布版本的配置返回error并计入unknownCount
+func GetNacosClientListener(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	ip := c.Query("ip")
+	tenant := c.Query("tenant")
+	if ip == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "ip is required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 查询客户端监听的配置
+	status, err := queryNacosListener(nacosUrl+"/v1/cs/listener", url.Values{
+		"ip":         {ip},
+		"tenant":     {tenant},
+		"all":        {"true"},
+		"sampleTime": {"1"},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch listeners: " + err.Error(),
+		})
+		return
+	}
+
+	configs := make([]nacosListenerStatus, 0, len(status))
+	for groupKey, md5 := range status {
+		dataId, group, configTenant := parseNacosGroupKey(groupKey)
+		configs = append(configs, nacosListenerStatus{DataId: dataId, Group: group, Tenant: configTenant, Md5: md5})
+	}
+
+	// 并发与当前发布版本比较，查询失败的配置返回error，不计入stale
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, nacosListenerConcurrency)
+	for i := range configs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item *nacosListenerStatus) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			detail, err := getNacosConfigDetail(nacosUrl, item.Tenant, item.DataId, item.Group)
+			if err != nil {
+				item.Error = err.Error()
+				return
+			}
+			item.Stale = detail == nil || detail.Md5 != item.Md5
+		}(&configs[i])
+	}
+	wg.Wait()
+
+	staleCount, unknownCount := 0, 0
+	for _, item := range configs {
+		switch {
+		case item.Error != "":
+			unknownCount++
+		case item.Stale:
+			staleCount++
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Group != configs[j].Group {
+			return configs[i].Group < configs[j].Group
+		}
+		return configs[i].DataId < configs[j].DataId
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"ip":           ip,
+		"staleCount":   staleCount,
+		"unknownCount": unknownCount,
+		"configs":      configs,
+	})
+}
+
+// queryNacosListener 调用nacos监听查询接口，返回监听项到md5的映射
+func queryNacosListener(apiUrl string, params url.Values) (map[string]string, error) {
+	resp, err := http.Get(apiUrl + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	// nacos返回字段名为lisentersGroupkeyStatus
+	var result struct {
+		CollectStatus int               `json:"collectStatus"`
+		Status        map[string]string `json:"lisentersGroupkeyStatus"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if result.Status == nil {
+		result.Status = map[string]string{}
+	}
+	return result.Status, nil
+}
+
+// parseNacosGroupKey 解析nacos的groupKey，格式为dataId+group或dataId+group+tenant，其中+、%会被转义
+func parseNacosGroupKey(groupKey string) (dataId string, group string, tenant string) {
+	parts := strings.Split(groupKey, "+")
+	unescape := func(s string) string {
+		return strings.NewReplacer("%2B", "+", "%25", "%").Replace(s)
+	}
+	if len(parts) > 0 {
+		dataId = unescape(parts[0])
+	}
+	if len(parts) > 1 {
+		group = unescape(parts[1])
+	}
+	if len(parts) > 2 {
+		tenant = unescape(parts[2])
+	}
+	return dataId, group, tenant
+}
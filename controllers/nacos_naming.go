@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNacosAllService 获取nacos的服务列表，query参数：namespaceId、groupName、pageNo、pageSize
+func GetNacosAllService(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	params := url.Values{
+		"namespaceId": {c.Query("namespaceId")},
+		"groupName":   {c.Query("groupName")},
+		"pageNo":      {c.DefaultQuery("pageNo", "1")},
+		"pageSize":    {c.DefaultQuery("pageSize", "100")},
+	}
+	resp, err := http.Get(nacosUrl + "/v1/ns/service/list?" + params.Encode())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch services: " + err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// 处理响应
+	var result struct {
+		Count int      `json:"count"`
+		Doms  []string `json:"doms"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	if result.Doms == nil {
+		result.Doms = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":    result.Count,
+		"services": result.Doms,
+	})
+}
+
+// GetNacosAllInstance 获取nacos服务的实例列表，query参数：namespaceId、groupName、serviceName
+// 返回实例的健康状态、权重、元数据和上下线状态
+func GetNacosAllInstance(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	serviceName := c.Query("serviceName")
+	if serviceName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "serviceName is required"})
+		return
+	}
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	params := url.Values{
+		"namespaceId": {c.Query("namespaceId")},
+		"groupName":   {c.Query("groupName")},
+		"serviceName": {serviceName},
+		"healthyOnly": {"false"},
+	}
+	resp, err := http.Get(nacosUrl + "/v1/ns/instance/list?" + params.Encode())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to fetch instances: " + err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// 处理响应
+	var result struct {
+		Hosts []struct {
+			Ip          string            `json:"ip"`
+			Port        int               `json:"port"`
+			ClusterName string            `json:"clusterName"`
+			Weight      float64           `json:"weight"`
+			Healthy     bool              `json:"healthy"`
+			Enabled     bool              `json:"enabled"`
+			Ephemeral   bool              `json:"ephemeral"`
+			Metadata    map[string]string `json:"metadata"`
+		} `json:"hosts"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result.Hosts)
+}
+
+// UpdateNacosInstance 修改nacos实例的权重、上下线状态或元数据，表单参数：namespaceId、groupName、serviceName、ip、port、clusterName、weight、enabled、metadata
+// weight、enabled、metadata只修改传入的字段，metadata为json格式
+func UpdateNacosInstance(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	serviceName := c.PostForm("serviceName")
+	ip := c.PostForm("ip")
+	port := c.PostForm("port")
+	if serviceName == "" || ip == "" || port == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "serviceName, ip and port are required"})
+		return
+	}
+
+	formData := url.Values{
+		"namespaceId": {c.PostForm("namespaceId")},
+		"groupName":   {c.PostForm("groupName")},
+		"serviceName": {serviceName},
+		"ip":          {ip},
+		"port":        {port},
+		"clusterName": {c.DefaultPostForm("clusterName", "DEFAULT")},
+	}
+	if weight, ok := c.GetPostForm("weight"); ok {
+		if _, err := strconv.ParseFloat(weight, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "weight must be a number"})
+			return
+		}
+		formData.Set("weight", weight)
+	}
+	if enabled, ok := c.GetPostForm("enabled"); ok {
+		if _, err := strconv.ParseBool(enabled); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "enabled must be true or false"})
+			return
+		}
+		formData.Set("enabled", enabled)
+	}
+	if metadata, ok := c.GetPostForm("metadata"); ok {
+		var m map[string]string
+		if err := json.Unmarshal([]byte(metadata), &m); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "metadata must be a json object of strings"})
+			return
+		}
+		formData.Set("metadata", metadata)
+	}
+
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 创建 PATCH 请求，只更新传入的字段
+	req, err := http.NewRequest("PATCH", nacosUrl+"/v1/ns/instance", strings.NewReader(formData.Encode()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// 发送请求
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// 处理响应
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": string(body),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StartNacosSnapshotJob 启动定时快照任务，启动时及之后每隔interval对开启快照的nacos拉取全部配置，interval<=0时不启动
+func StartNacosSnapshotJob(db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		// 启动时先做一次快照，配置未变化时不会生成新快照
+		snapshotAllNacos(db)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			snapshotAllNacos(db)
+		}
+	}()
+}
+
+// snapshotAllNacos 对所有开启快照的nacos做一次快照
+func snapshotAllNacos(db *gorm.DB) {
+	nacosList, err := model.ListSnapshotEnabledNacos(db)
+	if err != nil {
+		log.Printf("nacos snapshot: failed to list nacos: %v", err)
+		return
+	}
+	for _, nacos := range nacosList {
+		if _, _, err := takeNacosSnapshot(db, nacos.Name, nacos.URL); err != nil {
+			log.Printf("nacos snapshot: %s: %v", nacos.Name, err)
+		}
+	}
+}
+
+// CreateNacosSnapshot 立即对nacos做一次快照，配置与上次快照相同时不生成新快照
+func CreateNacosSnapshot(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	snapshot, created, err := takeNacosSnapshot(db, name, nacosUrl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to take snapshot: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"created": created, "snapshot": snapshot})
+}
+
+// RestoreNacosSnapshot 将快照恢复到任意nacos，表单参数：name（目标nacos，默认为快照来源）、tenant（只恢复该namespace）、targetTenant（恢复到其他namespace，需指定tenant）、policy（skip、overwrite，默认overwrite）
+func RestoreNacosSnapshot(c *gin.Context, db *gorm.DB) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid snapshot id"})
+		return
+	}
+	tenant, tenantSet := c.GetPostForm("tenant")
+	targetTenant, targetTenantSet := c.GetPostForm("targetTenant")
+	if targetTenantSet && !tenantSet {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "targetTenant requires tenant"})
+		return
+	}
+	policy := c.DefaultPostForm("policy", nacosSyncPolicyOverwrite)
+	if policy != nacosSyncPolicySkip && policy != nacosSyncPolicyOverwrite {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "policy must be skip or overwrite"})
+		return
+	}
+
+	var snapshot model.NacosSnapshot
+	if err := db.First(&snapshot, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "snapshot not found"})
+		return
+	}
+	name := c.DefaultPostForm("name", snapshot.NacosName)
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items, contents, err := model.GetNacosSnapshotContent(db, snapshot.ID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// 按namespace分组恢复
+	byTenant := make(map[string][]nacosConfigItem)
+	for _, item := range items {
+		if tenantSet && item.Tenant != tenant {
+			continue
+		}
+		dest := item.Tenant
+		if targetTenantSet {
+			dest = targetTenant
+		}
+		byTenant[dest] = append(byTenant[dest], nacosConfigItem{
+			DataId:  item.DataId,
+			Group:   item.Group,
+			Content: contents[item.ContentHash],
+			Type:    item.Type,
+			AppName: item.AppName,
+		})
+	}
+
+	result := make([]nacosSyncPlanItem, 0, len(items))
+	summary := map[string]int{"create": 0, "update": 0, "unchanged": 0, "skip": 0, "failed": 0}
+	for dest, restoreItems := range byTenant {
+		existingItems, err := listAllNacosConfig(nacosUrl, dest, "", "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch target configs: " + err.Error()})
+			return
+		}
+		existing := make(map[string]nacosConfigItem, len(existingItems))
+		for _, item := range existingItems {
+			existing[item.Group+"/"+item.DataId] = item
+		}
+		for _, item := range restoreItems {
+			action := "create"
+			if old, ok := existing[item.Group+"/"+item.DataId]; ok {
+				switch {
+				case old.Content == item.Content:
+					action = "unchanged"
+				case policy == nacosSyncPolicySkip:
+					action = "skip"
+				default:
+					action = "update"
+				}
+			}
+			planItem := nacosSyncPlanItem{DataId: item.DataId, Group: item.Group, Action: action}
+			if action == "create" || action == "update" {
+				if err := publishNacosConfig(nacosUrl, dest, item); err != nil {
+					planItem.Error = err.Error()
+					summary["failed"]++
+				}
+			}
+			summary[action]++
+			result = append(result, planItem)
+		}
+	}
+	status := http.StatusOK
+	if summary["failed"] > 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{"summary": summary, "items": result})
+}
+
+// takeNacosSnapshot 拉取nacos所有namespace的配置并保存为快照，与上次快照一致时返回上次快照且created为false
+func takeNacosSnapshot(db *gorm.DB, name string, nacosUrl string) (*model.NacosSnapshot, bool, error) {
+	tenants, err := listNacosNamespace(nacosUrl)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var items []model.NacosSnapshotItem
+	contents := make(map[string]string)
+	for _, tenant := range tenants {
+		configs, err := listAllNacosConfig(nacosUrl, tenant, "", "")
+		if err != nil {
+			return nil, false, err
+		}
+		for _, config := range configs {
+			sum := sha256.Sum256([]byte(config.Content))
+			hash := hex.EncodeToString(sum[:])
+			contents[hash] = config.Content
+			items = append(items, model.NacosSnapshotItem{
+				Tenant:      tenant,
+				Group:       config.Group,
+				DataId:      config.DataId,
+				Type:        config.Type,
+				AppName:     config.AppName,
+				ContentHash: hash,
+			})
+		}
+	}
+
+	// 计算快照整体hash，用于判断与上次快照是否一致
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.DataId < b.DataId
+	})
+	h := sha256.New()
+	for _, item := range items {
+		_, _ = io.WriteString(h, item.Tenant+"\x00"+item.Group+"\x00"+item.DataId+"\x00"+item.Type+"\x00"+item.ContentHash+"\n")
+	}
+	snapshotHash := hex.EncodeToString(h.Sum(nil))
+
+	latest, err := model.GetLatestNacosSnapshot(db, name)
+	if err != nil {
+		return nil, false, err
+	}
+	if latest != nil && latest.Hash == snapshotHash {
+		return latest, false, nil
+	}
+
+	snapshot := &model.NacosSnapshot{NacosName: name, Hash: snapshotHash, ConfigCount: len(items)}
+	if err := model.SaveNacosSnapshot(db, snapshot, items, contents); err != nil {
+		return nil, false, err
+	}
+	return snapshot, true, nil
+}
+
+// listNacosNamespace 获取nacos的所有namespace id，public为空字符串
+func listNacosNamespace(nacosUrl string) ([]string, error) {
+	resp, err := http.Get(nacosUrl + "/v1/console/namespaces")
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	var result struct {
+		Data []struct {
+			Namespace string `json:"namespace"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	tenants := make([]string, 0, len(result.Data))
+	for _, ns := range result.Data {
+		tenants = append(tenants, ns.Namespace)
+	}
+	return tenants, nil
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 冲突策略：目标已存在且内容不同的配置如何处理
+const (
+	nacosSyncPolicySkip      = "skip"
+	nacosSyncPolicyOverwrite = "overwrite"
+	nacosSyncPolicyAbort     = "abort"
+)
+
+// NacosSyncSource 同步的源，group、dataId为可选过滤条件
+type NacosSyncSource struct {
+	Name   string `json:"name" binding:"required"`
+	Tenant string `json:"tenant"`
+	Group  string `json:"group"`
+	DataId string `json:"dataId"`
+}
+
+// NacosSyncTarget 同步的目标，配置按源的group、dataId写入
+type NacosSyncTarget struct {
+	Name   string `json:"name" binding:"required"`
+	Tenant string `json:"tenant"`
+}
+
+// NacosSyncRequest 同步请求
+type NacosSyncRequest struct {
+	Source NacosSyncSource `json:"source" binding:"required"`
+	Target NacosSyncTarget `json:"target" binding:"required"`
+	Policy string          `json:"policy"`
+	DryRun bool            `json:"dryRun"`
+}
+
+// nacosSyncPlanItem 同步计划中的单个配置
+type nacosSyncPlanItem struct {
+	DataId string `json:"dataId"`
+	Group  string `json:"group"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SyncNacosConfig 在nacos实例、namespace之间复制同步配置，json参数：source、target、policy、dryRun
+// source中group、dataId为可选过滤条件；policy为skip、overwrite、abort，默认skip；dryRun为true时只返回计划
+func SyncNacosConfig(c *gin.Context, db *gorm.DB) {
+	var req NacosSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if req.Policy == "" {
+		req.Policy = nacosSyncPolicySkip
+	}
+	if req.Policy != nacosSyncPolicySkip && req.Policy != nacosSyncPolicyOverwrite && req.Policy != nacosSyncPolicyAbort {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "policy must be one of skip, overwrite, abort"})
+		return
+	}
+
+	// 获取源和目标nacos url
+	sourceUrl, err := model.GetNacosUrlByName(db, req.Source.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "source: " + err.Error()})
+		return
+	}
+	targetUrl, err := model.GetNacosUrlByName(db, req.Target.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "target: " + err.Error()})
+		return
+	}
+	if sourceUrl == targetUrl && req.Source.Tenant == req.Target.Tenant {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "source and target are the same namespace"})
+		return
+	}
+
+	// 拉取源和目标配置
+	sourceItems, err := listAllNacosConfig(sourceUrl, req.Source.Tenant, req.Source.Group, req.Source.DataId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch source configs: " + err.Error()})
+		return
+	}
+	// 目标按源的过滤条件拉取，与源中相同group/dataId的配置比较
+	targetItems, err := listAllNacosConfig(targetUrl, req.Target.Tenant, req.Source.Group, req.Source.DataId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch target configs: " + err.Error()})
+		return
+	}
+	existing := make(map[string]nacosConfigItem, len(targetItems))
+	for _, item := range targetItems {
+		existing[item.Group+"/"+item.DataId] = item
+	}
+
+	// 计算同步计划
+	plan := make([]nacosSyncPlanItem, 0, len(sourceItems))
+	summary := map[string]int{"create": 0, "update": 0, "unchanged": 0, "skip": 0}
+	conflict := false
+	for _, item := range sourceItems {
+		action := "create"
+		if old, ok := existing[item.Group+"/"+item.DataId]; ok {
+			switch {
+			case old.Content == item.Content:
+				action = "unchanged"
+			case req.Policy == nacosSyncPolicySkip:
+				action = "skip"
+			default:
+				action = "update"
+				conflict = true
+			}
+		}
+		summary[action]++
+		plan = append(plan, nacosSyncPlanItem{DataId: item.DataId, Group: item.Group, Action: action})
+	}
+
+	// abort策略下存在冲突时不执行
+	if req.Policy == nacosSyncPolicyAbort && conflict {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "target contains configs with different content, sync aborted",
+			"summary": summary,
+			"plan":    plan,
+		})
+		return
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"summary": summary, "plan": plan})
+		return
+	}
+
+	// 执行同步
+	failed := 0
+	for i, item := range sourceItems {
+		if plan[i].Action != "create" && plan[i].Action != "update" {
+			continue
+		}
+		if err := publishNacosConfig(targetUrl, req.Target.Tenant, item); err != nil {
+			plan[i].Error = err.Error()
+			failed++
+		}
+	}
+	summary["failed"] = failed
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{"summary": summary, "plan": plan})
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"codepub-service/sqlscan"
+	"context"
+	"database/sql"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SqlMaxRows 查询语句最多返回的行数，超出部分截断
+var SqlMaxRows = 1000
+
+// sqlColumn 结果集的列名和数据库类型
+type sqlColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// execSql 执行sql，查询语句返回列、行（最多SqlMaxRows行）和是否截断，写语句返回影响行数和最后插入的id
+func execSql(ctx context.Context, conn *sql.DB, dialect sqlscan.Dialect, query string) (gin.H, error) {
+	if !sqlscan.ReturnsRows(dialect, query) {
+		result, err := conn.ExecContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		// postgres驱动不支持LastInsertId，返回0
+		lastInsertId, _ := result.LastInsertId()
+		return gin.H{
+			"message":        "true",
+			"rows_affected":  rowsAffected,
+			"last_insert_id": lastInsertId,
+		}, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]sqlColumn, 0, len(columnTypes))
+	for _, ct := range columnTypes {
+		columns = append(columns, sqlColumn{Name: ct.Name(), Type: ct.DatabaseTypeName()})
+	}
+
+	data := make([][]interface{}, 0)
+	truncated := false
+	for rows.Next() {
+		if len(data) >= SqlMaxRows {
+			truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		// []byte转为字符串，避免被编码为base64
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		data = append(data, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"message":   "true",
+		"columns":   columns,
+		"rows":      data,
+		"truncated": truncated,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"codepub-service/sqlreview"
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListSqlReviewRule 列出实例生效的审核规则，query参数：kind（mysql、postgres）、instance
+// 未自定义的规则返回默认配置，custom为true表示已自定义，id用于删除自定义配置
+func ListSqlReviewRule(c *gin.Context, db *gorm.DB) {
+	kind := c.Query("kind")
+	instance := c.Query("instance")
+	configs, err := model.GetSqlReviewRules(db, kind, instance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	custom := make(map[string]model.SqlReviewRule, len(configs))
+	for _, config := range configs {
+		custom[config.Rule] = config
+	}
+
+	rules := make([]gin.H, 0, len(sqlreview.Rules))
+	for _, rule := range sqlreview.Rules {
+		item := gin.H{
+			"id":          0,
+			"rule":        rule.ID,
+			"description": rule.Description,
+			"enabled":     true,
+			"severity":    rule.Severity,
+			"threshold":   rule.Threshold,
+			"custom":      false,
+		}
+		if config, ok := custom[rule.ID]; ok {
+			item["id"] = config.ID
+			item["enabled"] = config.Enabled
+			item["severity"] = config.Severity
+			if config.Threshold > 0 {
+				item["threshold"] = config.Threshold
+			}
+			item["custom"] = true
+		}
+		rules = append(rules, item)
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// reviewSql 使用实例的规则配置审核sql，tableRows为nil时跳过依赖表行数的规则
+// tableRows只在alter_large_table开启且有ALTER TABLE语句时才会查询目标库，查询失败不会返回error
+func reviewSql(db *gorm.DB, dialect sqlreview.Dialect, instance string, query string, tableRows sqlreview.TableRowsFunc) (sqlreview.Result, error) {
+	rules, err := model.GetSqlReviewRules(db, string(dialect), instance)
+	if err != nil {
+		return sqlreview.Result{}, err
+	}
+	configs := make(map[string]sqlreview.RuleConfig, len(rules))
+	for _, rule := range rules {
+		configs[rule.Rule] = sqlreview.RuleConfig{
+			Enabled:   rule.Enabled,
+			Severity:  sqlreview.Severity(rule.Severity),
+			Threshold: rule.Threshold,
+		}
+	}
+	return sqlreview.Review(dialect, query, configs, tableRows), nil
+}
+
+// sqlReviewBlocked 审核不通过时写入响应：有error时返回400，有warning且未确认时返回409
+func sqlReviewBlocked(c *gin.Context, result sqlreview.Result, confirm bool) bool {
+	if result.Errors > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "sql review failed", "review": result})
+		return true
+	}
+	if result.Warnings > 0 && !confirm {
+		c.JSON(http.StatusConflict, gin.H{"message": "sql review warnings require confirmation", "review": result})
+		return true
+	}
+	return false
+}
+
+// sqlReviewLookupTimeout 审核时查询表行数的超时时间
+const sqlReviewLookupTimeout = 5 * time.Second
+
+// mysqlTableRows 从information_schema获取mysql表的估算行数，未指定schema时取同名表的最大值
+func mysqlTableRows(ctx context.Context, conn *sql.DB) sqlreview.TableRowsFunc {
+	return func(schema string, table string) (int64, error) {
+		query := "SELECT COALESCE(MAX(TABLE_ROWS), 0) FROM information_schema.TABLES WHERE TABLE_NAME = ?"
+		args := []interface{}{table}
+		if schema != "" {
+			query += " AND TABLE_SCHEMA = ?"
+			args = append(args, schema)
+		}
+		ctx, cancel := context.WithTimeout(ctx, sqlReviewLookupTimeout)
+		defer cancel()
+		var rows int64
+		err := conn.QueryRowContext(ctx, query, args...).Scan(&rows)
+		return rows, err
+	}
+}
+
+// postgresTableRows 从pg_class获取postgres表的估算行数，未指定schema时取同名表的最大值
+func postgresTableRows(ctx context.Context, conn *sql.DB) sqlreview.TableRowsFunc {
+	return func(schema string, table string) (int64, error) {
+		query := "SELECT COALESCE(MAX(c.reltuples), 0)::bigint FROM pg_class c " +
+			"JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.relkind IN ('r', 'p') AND c.relname = $1"
+		args := []interface{}{table}
+		if schema != "" {
+			query += " AND n.nspname = $2"
+			args = append(args, schema)
+		}
+		ctx, cancel := context.WithTimeout(ctx, sqlReviewLookupTimeout)
+		defer cancel()
+		var rows int64
+		err := conn.QueryRowContext(ctx, query, args...).Scan(&rows)
+		return rows, err
+	}
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/gorm"
+)
+
+// RestoreTrash 从回收站恢复etcd key或nacos配置，表单参数：force（为true时覆盖已存在的key或配置）
+// 目标已存在且未指定force时返回409，恢复成功后删除回收站条目
+func RestoreTrash(c *gin.Context, db *gorm.DB) {
+	id := c.Param("id")
+	force := c.PostForm("force") == "true"
+	var trash model.Trash
+	if err := db.First(&trash, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Record not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	switch trash.Kind {
+	case model.TrashKindEtcd:
+		if !restoreEtcdTrash(c, db, trash, force) {
+			return
+		}
+	case model.TrashKindNacos:
+		if !restoreNacosTrash(c, db, trash, force) {
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "unknown trash kind: " + trash.Kind})
+		return
+	}
+
+	db.Delete(&trash)
+	c.JSON(http.StatusOK, gin.H{"message": "true"})
+}
+
+// restoreEtcdTrash 恢复etcd key，失败时已写入响应
+func restoreEtcdTrash(c *gin.Context, db *gorm.DB, trash model.Trash, force bool) bool {
+	// 获取etcd配置
+	etcd, err := model.GetEtcdUrlByName(db, trash.Instance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	// 连接到etcd
+	cli, release, err := etcdClients.Get(etcd)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return false
+	}
+	defer release()
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(withEtcdPeer(context.Background()), 5*time.Second)
+	defer cancel()
+
+	key, value, err := decodeEtcdTrash(trash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	// 未指定force时只在key不存在时写入
+	txn := cli.Txn(ctx)
+	if !force {
+		txn = txn.If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+	}
+	resp, err := txn.Then(clientv3.OpPut(key, value)).Else(clientv3.OpGet(key)).Commit()
+	setEtcdEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error()})
+		return false
+	}
+	if !resp.Succeeded {
+		etcdConflict(c, resp)
+		return false
+	}
+	return true
+}
+
+// decodeEtcdTrash 返回回收站条目中原始的key和value
+func decodeEtcdTrash(trash model.Trash) (string, string, error) {
+	if trash.Encoding != model.TrashEncodingBase64 {
+		return trash.Key, trash.Value, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(trash.Key)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base64 key: %w", err)
+	}
+	value, err := base64.StdEncoding.DecodeString(trash.Value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base64 value: %w", err)
+	}
+	return string(key), string(value), nil
+}
+
+// restoreNacosTrash 恢复nacos配置，失败时已写入响应
+func restoreNacosTrash(c *gin.Context, db *gorm.DB, trash model.Trash, force bool) bool {
+	// 获取nacos url
+	nacosUrl, err := model.GetNacosUrlByName(db, trash.Instance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	if !force {
+		detail, err := getNacosConfigDetail(nacosUrl, trash.Tenant, trash.DataId, trash.Group)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return false
+		}
+		if detail != nil {
+			c.JSON(http.StatusConflict, gin.H{"message": "config already exists", "current": detail})
+			return false
+		}
+	}
+	item := nacosConfigItem{
+		DataId:  trash.DataId,
+		Group:   trash.Group,
+		Content: trash.Value,
+		Type:    trash.Type,
+		AppName: trash.AppName,
+	}
+	if err := publishNacosConfig(nacosUrl, trash.Tenant, item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+	return true
+}
+
+// StartTrashPurgeJob 启动回收站定时清理任务，每小时清理删除时间超过retention的条目，retention<=0时不启动
+func StartTrashPurgeJob(db *gorm.DB, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			count, err := model.PurgeTrash(db, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("trash purge: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("trash purge: removed %d entries", count)
+			}
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"codepub-service/model"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestEtcdTrashEncoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          string
+		value        string
+		wantEncoding string
+	}{
+		{name: "text", key: "/app/config", value: "a: 1", wantEncoding: ""},
+		{name: "binary value", key: "/app/bin", value: "\xff\x00\xfe", wantEncoding: model.TrashEncodingBase64},
+		{name: "binary key", key: "/app/\xff", value: "a: 1", wantEncoding: model.TrashEncodingBase64},
+		{name: "empty value", key: "/app/empty", value: "", wantEncoding: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trash := newEtcdTrash("a", &mvccpb.KeyValue{Key: []byte(tt.key), Value: []byte(tt.value)}, 1)
+			if trash.Encoding != tt.wantEncoding {
+				t.Errorf("Encoding = %q, want %q", trash.Encoding, tt.wantEncoding)
+			}
+			key, value, err := decodeEtcdTrash(trash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tt.key || value != tt.value {
+				t.Errorf("decoded = %q, %q, want %q, %q", key, value, tt.key, tt.value)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/redis"
@@ -11,6 +18,7 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	"codepub-service/controllers"
 	"codepub-service/middleware"
 	"codepub-service/model"
 	"codepub-service/routes"
@@ -45,6 +53,8 @@ func main() {
 	model.InitUserDB(db)
 	// 初始化nacos_config表
 	model.InitNacosDB(db)
+	// 初始化nacos快照相关表
+	model.InitNacosSnapshotDB(db)
 	// 初始化etcd_config表
 	model.InitEtcdDB(db)
 	// 初始化mysql_config表
@@ -53,6 +63,25 @@ func main() {
 	model.InitPostgresDB(db)
 	// 初始化jenkins_config表
 	model.InitJenkinsDB(db)
+	// 初始化sql_review_rule表
+	model.InitSqlReviewDB(db)
+	// 初始化回收站表
+	model.InitTrashDB(db)
+
+	// 启动nacos配置定时快照
+	controllers.StartNacosSnapshotJob(db, viper.GetDuration("nacos.snapshot.interval"))
+
+	// 启动etcd定时备份
+	controllers.StartEtcdSnapshotJob(db, viper.GetDuration("etcd.snapshot.interval"),
+		viper.GetString("etcd.snapshot.dir"), viper.GetInt("etcd.snapshot.retention"))
+
+	// sql查询最多返回的行数
+	if maxRows := viper.GetInt("sql.max_rows"); maxRows > 0 {
+		controllers.SqlMaxRows = maxRows
+	}
+
+	// 启动回收站定时清理
+	controllers.StartTrashPurgeJob(db, viper.GetDuration("trash.retention"))
 
 	// 从配置文件读取redis连接信息
 	redisAddr := viper.GetString("redis.address")
@@ -92,6 +121,8 @@ func main() {
 	routes.RegisterMysqlRoutes(r, db)
 	routes.RegisterPostgresRoutes(r, db)
 	routes.RegisterJenkinsRoutes(r, db)
+	routes.RegisterTrashRoutes(r, db)
+	routes.RegisterSqlReviewRoutes(r, db)
 
 	// 登出路由
 	r.POST("/api/v1/logout", func(c *gin.Context) {
@@ -101,9 +132,22 @@ func main() {
 	//r.GET("/", func(c *gin.Context) {})
 
 	// 运行服务器
-	err = r.Run(":8000")
-	if err != nil {
-		return
+	srv := &http.Server{
+		Addr:    ":8000",
+		Handler: r,
 	}
-
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to run server: %v", err)
+		}
+	}()
+
+	// 等待退出信号，关闭服务器并释放etcd客户端
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+	controllers.CloseEtcdClients()
 }
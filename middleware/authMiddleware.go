@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"codepub-service/model"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
 )
 
@@ -20,3 +22,17 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware 仅允许管理员访问
+func AdminMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		user := session.Get("user_id")
+		if user == nil || !model.IsAdminUser(db, user) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
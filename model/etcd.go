@@ -1,6 +1,7 @@
 package model
 
 import (
+	"codepub-service/crypt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"net/http"
@@ -9,9 +10,16 @@ import (
 
 // Etcd 数据模型
 type Etcd struct {
-	ID   uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
-	Name string `json:"name" gorm:"type:varchar(255);unique;not null;comment:'名称'"`
-	URL  string `json:"url" gorm:"type:varchar(255);not null;comment:'地址'"`
+	ID              uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	Name            string `json:"name" gorm:"type:varchar(255);unique;not null;comment:'名称'"`
+	URL             string `json:"url" gorm:"type:varchar(1024);not null;comment:'地址，多个用逗号分隔'"`
+	SRV             string `json:"srv" gorm:"type:varchar(255);comment:'DNS SRV发现域名'"`
+	Username        string `json:"username" gorm:"type:varchar(255);comment:'用户名'"`
+	Password        string `json:"password" gorm:"type:varchar(255);comment:'密码'"`
+	CACert          string `json:"caCert" gorm:"type:text;comment:'CA证书'"`
+	Cert            string `json:"cert" gorm:"type:text;comment:'客户端证书'"`
+	Key             string `json:"key" gorm:"type:text;comment:'客户端私钥'"`
+	SnapshotEnabled bool   `json:"snapshotEnabled" gorm:"not null;default:false;comment:'是否定时备份'"`
 }
 
 // TableName 指定表名为 etcd_config
@@ -19,6 +27,21 @@ func (Etcd) TableName() string {
 	return "etcd_config"
 }
 
+// etcdConfigListeners etcd_config更新或删除时的回调
+var etcdConfigListeners []func(name string)
+
+// RegisterEtcdConfigListener 注册etcd_config更新或删除时的回调，参数为变更前的名称
+func RegisterEtcdConfigListener(fn func(name string)) {
+	etcdConfigListeners = append(etcdConfigListeners, fn)
+}
+
+// notifyEtcdConfigChange 通知etcd_config已变更
+func notifyEtcdConfigChange(name string) {
+	for _, fn := range etcdConfigListeners {
+		fn(name)
+	}
+}
+
 // InitEtcdDB 初始化数据库
 func InitEtcdDB(db *gorm.DB) {
 	_ = db.AutoMigrate(&Etcd{})
@@ -31,6 +54,13 @@ func CreateEtcdConfig(c *gin.Context, db *gorm.DB) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
+	// password、证书、私钥进行加密
+	if err := encryptEtcdSecret(&etcd); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt password"})
+		return
+	}
+
 	if err := db.Create(&etcd).Error; err != nil {
 		if strings.Contains(err.Error(), "Duplicate entry") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "name already exist"})
@@ -42,26 +72,91 @@ func CreateEtcdConfig(c *gin.Context, db *gorm.DB) {
 	c.JSON(http.StatusOK, etcd)
 }
 
+// EtcdUpdateRequest 更新etcd_config的请求，Clear为需要清空的字段（password、caCert、cert、key），未列出的字段为空时保持原有值
+type EtcdUpdateRequest struct {
+	Etcd
+	Clear []string `json:"clear"`
+}
+
 // UpdateEtcdConfig 更新etcd_config
 func UpdateEtcdConfig(c *gin.Context, db *gorm.DB) {
 	id := c.Param("id")
 	var etcd Etcd
+
+	// 先获取现有的记录
 	if err := db.First(&etcd, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
 		return
 	}
-	if err := c.ShouldBindJSON(&etcd); err != nil {
+
+	// 绑定请求中的新数据
+	var request EtcdUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	db.Save(&etcd)
+	updatedData := request.Etcd
+	clearFields := make(map[string]bool, len(request.Clear))
+	for _, field := range request.Clear {
+		switch field {
+		case "password", "caCert", "cert", "key":
+			clearFields[field] = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clear field: " + field})
+			return
+		}
+	}
+
+	// password、证书、私钥非空时进行加密
+	if err := encryptEtcdSecret(&updatedData); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt password"})
+		return
+	}
+	// 为空时保持原有值不变，在clear中时清空
+	if updatedData.Password == "" && !clearFields["password"] {
+		updatedData.Password = etcd.Password
+	}
+	if updatedData.CACert == "" && !clearFields["caCert"] {
+		updatedData.CACert = etcd.CACert
+	}
+	if updatedData.Cert == "" && !clearFields["cert"] {
+		updatedData.Cert = etcd.Cert
+	}
+	if updatedData.Key == "" && !clearFields["key"] {
+		updatedData.Key = etcd.Key
+	}
+
+	// 更新其他字段
+	oldName := etcd.Name
+	etcd.Name = updatedData.Name
+	etcd.URL = updatedData.URL
+	etcd.SRV = updatedData.SRV
+	etcd.Username = updatedData.Username
+	etcd.Password = updatedData.Password
+	etcd.CACert = updatedData.CACert
+	etcd.Cert = updatedData.Cert
+	etcd.Key = updatedData.Key
+	etcd.SnapshotEnabled = updatedData.SnapshotEnabled
+
+	// 保存更新后的数据
+	if err := db.Save(&etcd).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	notifyEtcdConfigChange(oldName)
+
 	c.JSON(http.StatusOK, etcd)
 }
 
 // DeleteEtcdConfig 删除etcd_config
 func DeleteEtcdConfig(c *gin.Context, db *gorm.DB) {
 	id := c.Param("id")
-	result := db.Delete(&Etcd{}, id)
+	var etcd Etcd
+	if err := db.First(&etcd, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
+		return
+	}
+	result := db.Delete(&Etcd{}, etcd.ID)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
@@ -70,6 +165,7 @@ func DeleteEtcdConfig(c *gin.Context, db *gorm.DB) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
 		return
 	}
+	notifyEtcdConfigChange(etcd.Name)
 	c.JSON(http.StatusOK, gin.H{"message": "Record deleted"})
 }
 
@@ -81,10 +177,48 @@ func ListEtcdConfig(c *gin.Context, db *gorm.DB) {
 }
 
 // GetEtcdUrlByName 获取单个etcd_config
-func GetEtcdUrlByName(db *gorm.DB, name string) (url string, err error) {
-	var etcd Etcd
+func GetEtcdUrlByName(db *gorm.DB, name string) (etcd Etcd, err error) {
 	if err := db.Where("name = ?", name).First(&etcd).Error; err != nil {
-		return "", err
+		return etcd, err
 	}
-	return etcd.URL, nil
+	return etcd, nil
+}
+
+// DecryptEtcdSecret 解密etcd_config中的password、证书、私钥
+func DecryptEtcdSecret(etcd *Etcd) error {
+	encryptionKey := crypt.GetEncryptionKey()
+	for _, field := range []*string{&etcd.Password, &etcd.CACert, &etcd.Cert, &etcd.Key} {
+		if *field == "" {
+			continue
+		}
+		decrypted, err := crypt.Decrypt(encryptionKey, *field)
+		if err != nil {
+			return err
+		}
+		*field = decrypted
+	}
+	return nil
+}
+
+// encryptEtcdSecret 加密etcd_config中非空的password、证书、私钥
+func encryptEtcdSecret(etcd *Etcd) error {
+	encryptionKey := crypt.GetEncryptionKey()
+	for _, field := range []*string{&etcd.Password, &etcd.CACert, &etcd.Cert, &etcd.Key} {
+		if *field == "" {
+			continue
+		}
+		encrypted, err := crypt.Encrypt(encryptionKey, *field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// ListSnapshotEnabledEtcd 列出开启定时备份的etcd
+func ListSnapshotEnabledEtcd(db *gorm.DB) ([]Etcd, error) {
+	var etcd []Etcd
+	err := db.Where("snapshot_enabled = ?", true).Find(&etcd).Error
+	return etcd, err
 }
@@ -9,9 +9,10 @@ import (
 
 // Nacos 数据模型
 type Nacos struct {
-	ID   uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
-	Name string `json:"name" gorm:"type:varchar(255);unique;not null;comment:'名称'"`
-	URL  string `json:"url" gorm:"type:varchar(255);not null;comment:'地址'"`
+	ID              uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	Name            string `json:"name" gorm:"type:varchar(255);unique;not null;comment:'名称'"`
+	URL             string `json:"url" gorm:"type:varchar(255);not null;comment:'地址'"`
+	SnapshotEnabled bool   `json:"snapshotEnabled" gorm:"not null;default:false;comment:'是否定时快照'"`
 }
 
 // TableName 指定表名为 nacos_config
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"time"
+)
+
+// NacosSnapshot nacos配置快照
+type NacosSnapshot struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	NacosName   string    `json:"nacosName" gorm:"type:varchar(255);index;not null;comment:'nacos名称'"`
+	Hash        string    `json:"hash" gorm:"type:char(64);not null;comment:'快照整体hash'"`
+	ConfigCount int       `json:"configCount" gorm:"not null;comment:'配置数量'"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"comment:'创建时间'"`
+}
+
+// TableName 指定表名为 nacos_snapshot
+func (NacosSnapshot) TableName() string {
+	return "nacos_snapshot"
+}
+
+// NacosSnapshotItem 快照中的单个配置，内容通过hash关联 NacosSnapshotContent
+type NacosSnapshotItem struct {
+	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	SnapshotID  uint   `json:"snapshotId" gorm:"index;not null;comment:'快照id'"`
+	Tenant      string `json:"tenant" gorm:"type:varchar(128);not null;comment:'namespace'"`
+	Group       string `json:"group" gorm:"column:group_name;type:varchar(255);not null;comment:'group'"`
+	DataId      string `json:"dataId" gorm:"type:varchar(255);not null;comment:'dataId'"`
+	Type        string `json:"type" gorm:"type:varchar(64);comment:'配置类型'"`
+	AppName     string `json:"appName" gorm:"type:varchar(128);comment:'应用名'"`
+	ContentHash string `json:"contentHash" gorm:"type:char(64);not null;comment:'内容hash'"`
+}
+
+// TableName 指定表名为 nacos_snapshot_item
+func (NacosSnapshotItem) TableName() string {
+	return "nacos_snapshot_item"
+}
+
+// NacosSnapshotContent 快照内容，按hash去重存储
+type NacosSnapshotContent struct {
+	Hash    string `json:"hash" gorm:"primaryKey;type:char(64);comment:'内容sha256'"`
+	Content string `json:"content" gorm:"type:longtext;comment:'配置内容'"`
+}
+
+// TableName 指定表名为 nacos_snapshot_content
+func (NacosSnapshotContent) TableName() string {
+	return "nacos_snapshot_content"
+}
+
+// InitNacosSnapshotDB 初始化数据库
+func InitNacosSnapshotDB(db *gorm.DB) {
+	_ = db.AutoMigrate(&NacosSnapshot{}, &NacosSnapshotItem{}, &NacosSnapshotContent{})
+}
+
+// ListNacosSnapshot 列出某个nacos的快照，按时间倒序
+func ListNacosSnapshot(c *gin.Context, db *gorm.DB) {
+	name := c.Param("name")
+	var snapshots []NacosSnapshot
+	db.Where("nacos_name = ?", name).Order("id desc").Find(&snapshots)
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// GetNacosSnapshotItem 列出快照中的配置，query参数：content（为true时返回配置内容）
+func GetNacosSnapshotItem(c *gin.Context, db *gorm.DB) {
+	id := c.Param("id")
+	var snapshot NacosSnapshot
+	if err := db.First(&snapshot, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
+		return
+	}
+	items, contents, err := GetNacosSnapshotContent(db, snapshot.ID, c.Query("content") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	type itemDTO struct {
+		NacosSnapshotItem
+		Content *string `json:"content,omitempty"`
+	}
+	result := make([]itemDTO, 0, len(items))
+	for _, item := range items {
+		dto := itemDTO{NacosSnapshotItem: item}
+		if content, ok := contents[item.ContentHash]; ok {
+			dto.Content = &content
+		}
+		result = append(result, dto)
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshot": snapshot, "items": result})
+}
+
+// DeleteNacosSnapshot 删除快照，不再被引用的内容一并清理
+func DeleteNacosSnapshot(c *gin.Context, db *gorm.DB) {
+	id := c.Param("id")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&NacosSnapshot{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("snapshot_id = ?", id).Delete(&NacosSnapshotItem{}).Error; err != nil {
+			return err
+		}
+		// 锁定不再被引用的内容，与SaveNacosSnapshot互斥，锁定后再按引用情况删除，避免删掉刚被复用的内容
+		var hashes []string
+		if err := tx.Model(&NacosSnapshotContent{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("hash NOT IN (?)", tx.Model(&NacosSnapshotItem{}).Select("content_hash")).
+			Pluck("hash", &hashes).Error; err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+		return tx.Where("hash IN ? AND hash NOT IN (?)", hashes, tx.Model(&NacosSnapshotItem{}).Select("content_hash")).
+			Delete(&NacosSnapshotContent{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Record deleted"})
+}
+
+// GetNacosSnapshotContent 获取快照中的配置，withContent为true时同时返回hash到内容的映射
+func GetNacosSnapshotContent(db *gorm.DB, snapshotID uint, withContent bool) ([]NacosSnapshotItem, map[string]string, error) {
+	var items []NacosSnapshotItem
+	if err := db.Where("snapshot_id = ?", snapshotID).Order("tenant, group_name, data_id").Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	contents := make(map[string]string)
+	if !withContent || len(items) == 0 {
+		return items, contents, nil
+	}
+	hashes := make([]string, 0, len(items))
+	for _, item := range items {
+		hashes = append(hashes, item.ContentHash)
+	}
+	var rows []NacosSnapshotContent
+	if err := db.Where("hash IN ?", hashes).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		contents[row.Hash] = row.Content
+	}
+	return items, contents, nil
+}
+
+// GetLatestNacosSnapshot 获取某个nacos最近一次快照，不存在时返回nil
+func GetLatestNacosSnapshot(db *gorm.DB, name string) (*NacosSnapshot, error) {
+	var snapshots []NacosSnapshot
+	if err := db.Where("nacos_name = ?", name).Order("id desc").Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	return &snapshots[0], nil
+}
+
+// SaveNacosSnapshot 保存快照，内容已存在时不重复写入
+// 复用的内容行加FOR UPDATE锁直到快照条目写入，避免同时删除快照时被当作未引用的内容清理
+func SaveNacosSnapshot(db *gorm.DB, snapshot *NacosSnapshot, items []NacosSnapshotItem, contents map[string]string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for hash, content := range contents {
+			row := NacosSnapshotContent{Hash: hash, Content: content}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(NacosSnapshotContent{Hash: hash}).FirstOrCreate(&row).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].SnapshotID = snapshot.ID
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, 200).Error
+	})
+}
+
+// ListSnapshotEnabledNacos 列出开启定时快照的nacos
+func ListSnapshotEnabledNacos(db *gorm.DB) ([]Nacos, error) {
+	var nacos []Nacos
+	err := db.Where("snapshot_enabled = ?", true).Find(&nacos).Error
+	return nacos, err
+}
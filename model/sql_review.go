@@ -0,0 +1,91 @@
+package model
+
+import (
+	"codepub-service/sqlreview"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// SqlReviewRule 实例的sql审核规则配置，未配置的规则使用默认配置
+type SqlReviewRule struct {
+	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	Kind      string `json:"kind" gorm:"type:varchar(32);uniqueIndex:idx_sql_review_rule;not null;comment:'类型，mysql或postgres'"`
+	Instance  string `json:"instance" gorm:"type:varchar(255);uniqueIndex:idx_sql_review_rule;not null;comment:'mysql或postgres名称'"`
+	Rule      string `json:"rule" gorm:"type:varchar(64);uniqueIndex:idx_sql_review_rule;not null;comment:'规则id'"`
+	Enabled   bool   `json:"enabled" gorm:"not null;comment:'是否启用'"`
+	Severity  string `json:"severity" gorm:"type:varchar(16);not null;comment:'级别，error或warning'"`
+	Threshold int64  `json:"threshold" gorm:"comment:'阈值，<=0时使用默认值'"`
+}
+
+// TableName 指定表名为 sql_review_rule
+func (SqlReviewRule) TableName() string {
+	return "sql_review_rule"
+}
+
+// InitSqlReviewDB 初始化数据库
+func InitSqlReviewDB(db *gorm.DB) {
+	_ = db.AutoMigrate(&SqlReviewRule{})
+}
+
+// SaveSqlReviewRule 新增或更新实例的规则配置，按kind、instance、rule唯一
+func SaveSqlReviewRule(c *gin.Context, db *gorm.DB) {
+	var rule SqlReviewRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule.Kind != string(sqlreview.MySQL) && rule.Kind != string(sqlreview.Postgres) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be mysql or postgres"})
+		return
+	}
+	if rule.Instance == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instance is required"})
+		return
+	}
+	if _, ok := sqlreview.FindRule(rule.Rule); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown rule: " + rule.Rule})
+		return
+	}
+	if !sqlreview.ValidSeverity(sqlreview.Severity(rule.Severity)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "severity must be error or warning"})
+		return
+	}
+
+	// 已存在则更新
+	var existing SqlReviewRule
+	err := db.Where("kind = ? AND instance = ? AND rule = ?", rule.Kind, rule.Instance, rule.Rule).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID = existing.ID
+	if err := db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteSqlReviewRule 删除实例的规则配置，恢复为默认配置
+func DeleteSqlReviewRule(c *gin.Context, db *gorm.DB) {
+	id := c.Param("id")
+	result := db.Delete(&SqlReviewRule{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Record deleted"})
+}
+
+// GetSqlReviewRules 获取实例的规则配置
+func GetSqlReviewRules(db *gorm.DB, kind string, instance string) ([]SqlReviewRule, error) {
+	var rules []SqlReviewRule
+	err := db.Where("kind = ? AND instance = ?", kind, instance).Find(&rules).Error
+	return rules, err
+}
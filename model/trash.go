@@ -0,0 +1,80 @@
+package model
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// TrashEncodingBase64 key和value使用base64编码
+const TrashEncodingBase64 = "base64"
+
+// 回收站条目类型
+const (
+	TrashKindEtcd  = "etcd"
+	TrashKindNacos = "nacos"
+)
+
+// Trash 回收站，保存被删除的etcd key和nacos配置
+// etcd的key或value不是有效的utf8时，key和value均以base64保存，Encoding为base64
+type Trash struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
+	Kind      string    `json:"kind" gorm:"type:varchar(32);index:idx_trash_instance;not null;comment:'类型，etcd或nacos'"`
+	Instance  string    `json:"instance" gorm:"type:varchar(255);index:idx_trash_instance;not null;comment:'etcd或nacos名称'"`
+	Key       string    `json:"key" gorm:"type:mediumtext;comment:'etcd key'"`
+	Tenant    string    `json:"tenant" gorm:"type:varchar(128);comment:'nacos namespace'"`
+	Group     string    `json:"group" gorm:"column:group_name;type:varchar(255);comment:'nacos group'"`
+	DataId    string    `json:"dataId" gorm:"type:varchar(255);comment:'nacos dataId'"`
+	Type      string    `json:"type" gorm:"type:varchar(64);comment:'nacos配置类型'"`
+	AppName   string    `json:"appName" gorm:"type:varchar(128);comment:'nacos应用名'"`
+	Value     string    `json:"value" gorm:"type:longtext;comment:'删除前的内容'"`
+	Encoding  string    `json:"encoding,omitempty" gorm:"type:varchar(16);comment:'为base64时key和value均为base64编码'"`
+	DeletedBy uint      `json:"deletedBy" gorm:"comment:'删除人id'"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index;comment:'删除时间'"`
+}
+
+// TableName 指定表名为 trash
+func (Trash) TableName() string {
+	return "trash"
+}
+
+// InitTrashDB 初始化数据库
+func InitTrashDB(db *gorm.DB) {
+	_ = db.AutoMigrate(&Trash{})
+}
+
+// ListTrash 列出回收站，query参数：kind、instance
+func ListTrash(c *gin.Context, db *gorm.DB) {
+	query := db.Order("id desc")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if instance := c.Query("instance"); instance != "" {
+		query = query.Where("instance = ?", instance)
+	}
+	var trash []Trash
+	query.Find(&trash)
+	c.JSON(http.StatusOK, trash)
+}
+
+// DeleteTrash 彻底删除回收站中的条目
+func DeleteTrash(c *gin.Context, db *gorm.DB) {
+	id := c.Param("id")
+	result := db.Delete(&Trash{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Record not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Record deleted"})
+}
+
+// PurgeTrash 清理删除时间早于before的条目
+func PurgeTrash(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("created_at < ?", before).Delete(&Trash{})
+	return result.RowsAffected, result.Error
+}
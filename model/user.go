@@ -15,12 +15,21 @@ type User struct {
 	Id       uint   `json:"id" gorm:"primaryKey;autoIncrement;comment:'自增id'"`
 	Username string `json:"username" gorm:"type:varchar(255);unique;not null;comment:'用户名'"`
 	Password string `json:"password" gorm:"type:varchar(255);not null;comment:'密码'"`
+	IsAdmin  bool   `json:"isAdmin" gorm:"not null;default:false;comment:'是否管理员'"`
 }
 
 // UserDTO 返回给前端的数据
 type UserDTO struct {
 	Id       uint   `json:"id"`
 	Username string `json:"username"`
+	IsAdmin  bool   `json:"isAdmin"`
+}
+
+// UserRequest 创建或更新user的请求，isAdmin为空时不修改管理员标记
+type UserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	IsAdmin  *bool  `json:"isAdmin"`
 }
 
 // InitUserDB 初始化数据库
@@ -42,8 +51,12 @@ func InitUserDB(db *gorm.DB) {
 		adminUser := User{
 			Username: "admin",
 			Password: password,
+			IsAdmin:  true,
 		}
 		db.Create(&adminUser)
+	} else if result.Error == nil && !user.IsAdmin {
+		// 已存在的admin用户升级为管理员
+		db.Model(&user).Update("is_admin", true)
 	}
 }
 
@@ -59,13 +72,21 @@ func checkPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// CreateUser 创建user
+// CreateUser 创建user，只有管理员可以设置isAdmin
 func CreateUser(c *gin.Context, db *gorm.DB) {
-	var user User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req UserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.IsAdmin != nil && !IsAdminUser(db, CurrentUserID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admin can set isAdmin"})
+		return
+	}
+	user := User{Username: req.Username, Password: req.Password}
+	if req.IsAdmin != nil {
+		user.IsAdmin = *req.IsAdmin
+	}
 
 	// password进行加密
 	password, err := hashPassword(user.Password)
@@ -86,7 +107,7 @@ func CreateUser(c *gin.Context, db *gorm.DB) {
 	c.JSON(http.StatusOK, user)
 }
 
-// UpdateUser 更新user
+// UpdateUser 更新user，请求中带isAdmin时才修改管理员标记，且只有管理员可以修改
 func UpdateUser(c *gin.Context, db *gorm.DB) {
 	id := c.Param("id")
 	var user User
@@ -98,11 +119,15 @@ func UpdateUser(c *gin.Context, db *gorm.DB) {
 	}
 
 	// 绑定请求中的新数据
-	var updatedData User
+	var updatedData UserRequest
 	if err := c.ShouldBindJSON(&updatedData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if updatedData.IsAdmin != nil && !IsAdminUser(db, CurrentUserID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admin can set isAdmin"})
+		return
+	}
 
 	// 检查密码是否为空
 	if updatedData.Password == "" {
@@ -121,6 +146,9 @@ func UpdateUser(c *gin.Context, db *gorm.DB) {
 	// 更新其他字段
 	user.Username = updatedData.Username
 	user.Password = updatedData.Password
+	if updatedData.IsAdmin != nil {
+		user.IsAdmin = *updatedData.IsAdmin
+	}
 
 	// 保存更新后的数据
 	if err := db.Save(&user).Error; err != nil {
@@ -156,6 +184,7 @@ func GetUser(c *gin.Context, db *gorm.DB) {
 		userDTOs = append(userDTOs, UserDTO{
 			Id:       user.Id,
 			Username: user.Username,
+			IsAdmin:  user.IsAdmin,
 		})
 	}
 
@@ -222,7 +251,26 @@ func GetUserInfo(c *gin.Context, db *gorm.DB) {
 	userDTO := UserDTO{
 		Id:       user.Id,
 		Username: user.Username,
+		IsAdmin:  user.IsAdmin,
 	}
 
 	c.JSON(http.StatusOK, userDTO)
 }
+
+// IsAdminUser 判断用户是否为管理员
+func IsAdminUser(db *gorm.DB, userID interface{}) bool {
+	var user User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
+
+// CurrentUserID 获取当前会话的用户id，未登录时返回0
+func CurrentUserID(c *gin.Context) uint {
+	session := sessions.Default(c)
+	if id, ok := session.Get("user_id").(uint); ok {
+		return id
+	}
+	return 0
+}
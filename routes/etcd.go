@@ -2,6 +2,7 @@ package routes
 
 import (
 	"codepub-service/controllers"
+	"codepub-service/middleware"
 	"codepub-service/model"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -9,33 +10,130 @@ import (
 
 func RegisterEtcdRoutes(r *gin.Engine, db *gorm.DB) {
 	// --------------------------------etcd api-------------------------------------
-	// 通过name获取对应etcd地址的所有keys
+	// 通过name获取对应etcd地址的所有keys，其中query参数：prefix
 	r.GET("/api/v1/etcd/key/:name", func(c *gin.Context) {
 		controllers.GetEtcdAllKeys(c, db)
 	})
+	// 通过name分页浏览对应etcd地址的keys，其中query参数：prefix、separator、dir、count、limit、continue
+	r.GET("/api/v1/etcd/browse/:name", func(c *gin.Context) {
+		controllers.BrowseEtcdKeys(c, db)
+	})
 	// 通过name获取对应etcd地址的value，其中query参数：key
 	r.GET("/api/v1/etcd/value/:name", func(c *gin.Context) {
 		controllers.GetEtcdValueByKey(c, db)
 	})
-	// 通过name创建或修改对应etcd地址的config，其中表单参数：：key、value
+	// 通过name创建或修改对应etcd地址的config，其中表单参数：：key、value、revision、ttl
 	r.POST("/api/v1/etcd/config/:name", func(c *gin.Context) {
 		controllers.SaveEtcdValueByKey(c, db)
 	})
-	// 通过name删除对应etcd地址的config，其中query参数：：key
+	// 通过name删除对应etcd地址的config，其中query参数：：key、revision
 	r.DELETE("/api/v1/etcd/key/:name", func(c *gin.Context) {
 		controllers.DeleteEtcdValueByKey(c, db)
 	})
+	// 通过name获取对应etcd地址key的历史版本，其中query参数：key、limit
+	r.GET("/api/v1/etcd/history/:name", func(c *gin.Context) {
+		controllers.GetEtcdKeyHistory(c, db)
+	})
+	// 通过name将对应etcd地址的key回滚到历史版本，其中表单参数：key、revision、expectedRevision
+	r.POST("/api/v1/etcd/rollback/:name", func(c *gin.Context) {
+		controllers.RollbackEtcdKey(c, db)
+	})
+	// 通过name以SSE方式推送对应etcd地址key的变更，其中query参数：key、prefix、revision
+	r.GET("/api/v1/etcd/watch/:name", func(c *gin.Context) {
+		controllers.WatchEtcdKey(c, db)
+	})
+	// 通过name导出对应etcd地址前缀下的keys，其中query参数：prefix、format（json、yaml）、base64
+	r.GET("/api/v1/etcd/export/:name", func(c *gin.Context) {
+		controllers.ExportEtcdKeys(c, db)
+	})
+	// 通过name导入keys到对应etcd地址，其中表单参数：file、mode（merge、overwrite、replace-prefix）、prefix
+	r.POST("/api/v1/etcd/import/:name", func(c *gin.Context) {
+		controllers.ImportEtcdKeys(c, db)
+	})
+	// 通过name在对应etcd地址原子执行多个put、delete操作，其中json参数：operations
+	r.POST("/api/v1/etcd/txn/:name", func(c *gin.Context) {
+		controllers.ExecEtcdTxn(c, db)
+	})
+	// 通过name获取对应etcd地址的所有lease及绑定的key，其中query参数：keys（为false时不返回绑定的key）
+	r.GET("/api/v1/etcd/lease/:name", func(c *gin.Context) {
+		controllers.GetEtcdAllLease(c, db)
+	})
+	// 通过name续约对应etcd地址的lease，其中表单参数：id
+	r.POST("/api/v1/etcd/lease/:name", func(c *gin.Context) {
+		controllers.KeepAliveEtcdLease(c, db)
+	})
+	// 通过name撤销对应etcd地址的lease，其中query参数：id
+	r.DELETE("/api/v1/etcd/lease/:name", func(c *gin.Context) {
+		controllers.RevokeEtcdLease(c, db)
+	})
+	// 通过name获取对应etcd地址的集群成员、节点状态和告警
+	r.GET("/api/v1/etcd/cluster/:name", func(c *gin.Context) {
+		controllers.GetEtcdCluster(c, db)
+	})
+	// 通过name对对应etcd地址做碎片整理，其中表单参数：endpoint（必须为集群成员地址），仅管理员
+	r.POST("/api/v1/etcd/defragment/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.DefragmentEtcd(c, db)
+	})
+	// 通过name压缩对应etcd地址的历史版本，其中表单参数：revision（必填）、physical，仅管理员
+	r.POST("/api/v1/etcd/compact/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.CompactEtcd(c, db)
+	})
+	// 通过name下载对应etcd地址的快照文件，仅管理员
+	r.GET("/api/v1/etcd/snapshot/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.DownloadEtcdSnapshot(c, db)
+	})
+	// 通过name获取对应etcd地址的用户列表，仅管理员
+	r.GET("/api/v1/etcd/user/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.GetEtcdAllUser(c, db)
+	})
+	// 通过name新增对应etcd地址的用户，其中表单参数：user、password，仅管理员
+	r.POST("/api/v1/etcd/user/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.CreateEtcdUser(c, db)
+	})
+	// 通过name删除对应etcd地址的用户，其中query参数：user，仅管理员
+	r.DELETE("/api/v1/etcd/user/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.DeleteEtcdUser(c, db)
+	})
+	// 通过name为对应etcd地址的用户授予角色，其中表单参数：user、role，仅管理员
+	r.POST("/api/v1/etcd/user_role/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.GrantEtcdUserRole(c, db)
+	})
+	// 通过name撤销对应etcd地址用户的角色，其中query参数：user、role，仅管理员
+	r.DELETE("/api/v1/etcd/user_role/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.RevokeEtcdUserRole(c, db)
+	})
+	// 通过name获取对应etcd地址的角色列表，仅管理员
+	r.GET("/api/v1/etcd/role/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.GetEtcdAllRole(c, db)
+	})
+	// 通过name新增对应etcd地址的角色，其中表单参数：role，仅管理员
+	r.POST("/api/v1/etcd/role/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.CreateEtcdRole(c, db)
+	})
+	// 通过name删除对应etcd地址的角色，其中query参数：role，仅管理员
+	r.DELETE("/api/v1/etcd/role/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.DeleteEtcdRole(c, db)
+	})
+	// 通过name为对应etcd地址的角色授予权限，其中表单参数：role、key、rangeEnd、prefix、permType，仅管理员
+	r.POST("/api/v1/etcd/role_permission/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.GrantEtcdRolePermission(c, db)
+	})
+	// 通过name撤销对应etcd地址角色的权限，其中query参数：role、key、rangeEnd、prefix，仅管理员
+	r.DELETE("/api/v1/etcd/role_permission/:name", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		controllers.RevokeEtcdRolePermission(c, db)
+	})
 
 	// --------------------------------etcd表-------------------------------------
 	// 获取etcd_config表中的配置列表
 	r.GET("/api/v1/etcd_config/list", func(c *gin.Context) {
 		model.ListEtcdConfig(c, db)
 	})
-	// 新增etcd_config表中的配置，提交字段name、url
+	// 新增etcd_config表中的配置，提交字段name、url（多个用逗号分隔）、srv、username、password、caCert、cert、key、snapshotEnabled
 	r.POST("/api/v1/etcd_config/list", func(c *gin.Context) {
 		model.CreateEtcdConfig(c, db)
 	})
-	// 通过id更新etcd_config表中的配置，提交字段name、url
+	// 通过id更新etcd_config表中的配置，提交字段name、url（多个用逗号分隔）、srv、username、password、caCert、cert、key、snapshotEnabled、clear
+	// password、caCert、cert、key为空时保持原有值，需要删除时在clear中列出，如"clear": ["password", "caCert", "cert", "key"]
 	r.PUT("/api/v1/etcd_config/:id", func(c *gin.Context) {
 		model.UpdateEtcdConfig(c, db)
 	})
@@ -9,10 +9,14 @@ import (
 
 func RegisterMysqlRoutes(r *gin.Engine, db *gorm.DB) {
 	// --------------------------------mysql api-------------------------------------
-	// 通过name获取对应的mysql地址，并执行sql，其中表单参数：sql
+	// 通过name获取对应的mysql地址，并执行sql，其中表单参数：sql、confirm
 	r.POST("/api/v1/mysql/sql/:name", func(c *gin.Context) {
 		controllers.ExecMysqlSql(c, db)
 	})
+	// 通过name获取对应的mysql地址，只审核sql不执行，其中表单参数：sql
+	r.POST("/api/v1/mysql/review/:name", func(c *gin.Context) {
+		controllers.ReviewMysqlSql(c, db)
+	})
 
 	// --------------------------------mysql表-------------------------------------
 	// 获取mysql_config表中的配置列表
@@ -13,10 +13,30 @@ func RegisterNacosRoutes(r *gin.Engine, db *gorm.DB) {
 	r.GET("/api/v1/nacos/namespace/:name", func(c *gin.Context) {
 		controllers.GetNacosAllNamespace(c, db)
 	})
-	// 通过name获取对应nacos地址的所有config，其中query参数：pageSize、tenant
+	// 通过name在对应nacos地址新增namespace，其中表单参数：namespaceId（可选）、namespaceName、namespaceDesc
+	r.POST("/api/v1/nacos/namespace/:name", func(c *gin.Context) {
+		controllers.CreateNacosNamespace(c, db)
+	})
+	// 通过name修改对应nacos地址的namespace，其中表单参数：namespaceId、namespaceName、namespaceDesc
+	r.PUT("/api/v1/nacos/namespace/:name", func(c *gin.Context) {
+		controllers.UpdateNacosNamespace(c, db)
+	})
+	// 通过name删除对应nacos地址的namespace，其中query参数：namespaceId、force（namespace下有配置时需force=true）
+	r.DELETE("/api/v1/nacos/namespace/:name", func(c *gin.Context) {
+		controllers.DeleteNacosNamespace(c, db)
+	})
+	// 通过name获取对应nacos地址的集群节点及健康状态
+	r.GET("/api/v1/nacos/cluster/:name", func(c *gin.Context) {
+		controllers.GetNacosCluster(c, db)
+	})
+	// 通过name分页获取对应nacos地址的config，其中query参数：tenant、pageNo、pageSize、dataId、group、appName、tags、search（accurate、blur）
 	r.GET("/api/v1/nacos/config/:name", func(c *gin.Context) {
 		controllers.GetNacosAllConfig(c, db)
 	})
+	// 通过name获取对应nacos地址的单个config及元数据，其中query参数：tenant、dataId、group
+	r.GET("/api/v1/nacos/config/:name/detail", func(c *gin.Context) {
+		controllers.GetNacosConfig(c, db)
+	})
 	// 通过name创建或修改对应nacos地址的config，其中表单参数：：tenant、dataId、group、content、type
 	r.POST("/api/v1/nacos/config/:name", func(c *gin.Context) {
 		controllers.SaveNacosConfig(c, db)
@@ -25,17 +45,69 @@ func RegisterNacosRoutes(r *gin.Engine, db *gorm.DB) {
 	r.DELETE("/api/v1/nacos/config/:name", func(c *gin.Context) {
 		controllers.DeleteNacosConfig(c, db)
 	})
+	// 在nacos实例、namespace之间同步config，其中json参数：source（name、tenant、group、dataId）、target（name、tenant）、policy、dryRun
+	r.POST("/api/v1/nacos/sync", func(c *gin.Context) {
+		controllers.SyncNacosConfig(c, db)
+	})
+	// 通过name导出对应nacos地址的config归档，其中query参数：tenant、group、dataId、format（zip、tar）
+	r.GET("/api/v1/nacos/export/:name", func(c *gin.Context) {
+		controllers.ExportNacosConfig(c, db)
+	})
+	// 通过name导入config归档到对应nacos地址，其中表单参数：file、tenant、policy（skip、overwrite）
+	r.POST("/api/v1/nacos/import/:name", func(c *gin.Context) {
+		controllers.ImportNacosConfig(c, db)
+	})
+	// 通过name获取对应nacos地址的服务列表，其中query参数：namespaceId、groupName、pageNo、pageSize
+	r.GET("/api/v1/nacos/service/:name", func(c *gin.Context) {
+		controllers.GetNacosAllService(c, db)
+	})
+	// 通过name获取对应nacos地址的服务实例列表，其中query参数：namespaceId、groupName、serviceName
+	r.GET("/api/v1/nacos/instance/:name", func(c *gin.Context) {
+		controllers.GetNacosAllInstance(c, db)
+	})
+	// 通过name修改对应nacos地址的服务实例，其中表单参数：namespaceId、groupName、serviceName、ip、port、clusterName、weight、enabled、metadata
+	r.PUT("/api/v1/nacos/instance/:name", func(c *gin.Context) {
+		controllers.UpdateNacosInstance(c, db)
+	})
+	// 通过name获取对应nacos地址中监听某个config的客户端，其中query参数：tenant、dataId、group
+	r.GET("/api/v1/nacos/listener/config/:name", func(c *gin.Context) {
+		controllers.GetNacosConfigListener(c, db)
+	})
+	// 通过name获取对应nacos地址中某个客户端ip监听的config，其中query参数：ip、tenant
+	r.GET("/api/v1/nacos/listener/client/:name", func(c *gin.Context) {
+		controllers.GetNacosClientListener(c, db)
+	})
+	// 通过name获取对应nacos的配置快照列表
+	r.GET("/api/v1/nacos/snapshot/:name", func(c *gin.Context) {
+		model.ListNacosSnapshot(c, db)
+	})
+	// 通过name立即对对应nacos的配置做快照
+	r.POST("/api/v1/nacos/snapshot/:name", func(c *gin.Context) {
+		controllers.CreateNacosSnapshot(c, db)
+	})
+	// 通过id获取快照中的config，其中query参数：content
+	r.GET("/api/v1/nacos/snapshot_item/:id", func(c *gin.Context) {
+		model.GetNacosSnapshotItem(c, db)
+	})
+	// 通过id删除快照
+	r.DELETE("/api/v1/nacos/snapshot_item/:id", func(c *gin.Context) {
+		model.DeleteNacosSnapshot(c, db)
+	})
+	// 通过id将快照恢复到nacos，其中表单参数：name、tenant、targetTenant、policy（skip、overwrite）
+	r.POST("/api/v1/nacos/snapshot_restore/:id", func(c *gin.Context) {
+		controllers.RestoreNacosSnapshot(c, db)
+	})
 
 	// --------------------------------nacos表-------------------------------------
 	// 获取nacos_config表中的配置列表
 	r.GET("/api/v1/nacos_config/list", func(c *gin.Context) {
 		model.ListNacosConfig(c, db)
 	})
-	// 新增nacos_config表中的配置，提交字段name、url
+	// 新增nacos_config表中的配置，提交字段name、url、snapshotEnabled
 	r.POST("/api/v1/nacos_config/list", func(c *gin.Context) {
 		model.CreateNacosConfig(c, db)
 	})
-	// 通过id更新nacos_config表中的配置，提交字段name、url
+	// 通过id更新nacos_config表中的配置，提交字段name、url、snapshotEnabled
 	r.PUT("/api/v1/nacos_config/:id", func(c *gin.Context) {
 		model.UpdateNacosConfig(c, db)
 	})
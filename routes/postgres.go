@@ -9,10 +9,14 @@ import (
 
 func RegisterPostgresRoutes(r *gin.Engine, db *gorm.DB) {
 	// --------------------------------postgres api-------------------------------------
-	// 通过name获取对应的postgres地址，并执行sql，其中表单参数：sql
+	// 通过name获取对应的postgres地址，并执行sql，其中表单参数：sql、confirm
 	r.POST("/api/v1/postgres/sql/:name", func(c *gin.Context) {
 		controllers.ExecPostgresSql(c, db)
 	})
+	// 通过name获取对应的postgres地址，只审核sql不执行，其中表单参数：sql
+	r.POST("/api/v1/postgres/review/:name", func(c *gin.Context) {
+		controllers.ReviewPostgresSql(c, db)
+	})
 
 	// --------------------------------postgres表-------------------------------------
 	// 获取postgres_config表中的配置列表
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"codepub-service/controllers"
+	"codepub-service/middleware"
+	"codepub-service/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterSqlReviewRoutes(r *gin.Engine, db *gorm.DB) {
+	// --------------------------------sql审核规则-------------------------------------
+	// 获取实例生效的sql审核规则，其中query参数：kind（mysql、postgres）、instance
+	r.GET("/api/v1/sql_review/rule", func(c *gin.Context) {
+		controllers.ListSqlReviewRule(c, db)
+	})
+	// 新增或更新实例的sql审核规则，提交字段kind、instance、rule、enabled、severity、threshold，仅管理员可用
+	r.POST("/api/v1/sql_review/rule", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		model.SaveSqlReviewRule(c, db)
+	})
+	// 通过id删除实例的sql审核规则，恢复为默认配置，仅管理员可用
+	r.DELETE("/api/v1/sql_review/rule/:id", middleware.AdminMiddleware(db), func(c *gin.Context) {
+		model.DeleteSqlReviewRule(c, db)
+	})
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"codepub-service/controllers"
+	"codepub-service/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RegisterTrashRoutes(r *gin.Engine, db *gorm.DB) {
+	// --------------------------------回收站-------------------------------------
+	// 列出回收站中被删除的etcd key和nacos配置，其中query参数：kind（etcd、nacos）、instance
+	r.GET("/api/v1/trash", func(c *gin.Context) {
+		model.ListTrash(c, db)
+	})
+	// 通过id从回收站恢复，其中表单参数：force
+	r.POST("/api/v1/trash/restore/:id", func(c *gin.Context) {
+		controllers.RestoreTrash(c, db)
+	})
+	// 通过id彻底删除回收站中的条目
+	r.DELETE("/api/v1/trash/:id", func(c *gin.Context) {
+		model.DeleteTrash(c, db)
+	})
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"codepub-service/middleware"
 	"codepub-service/model"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -11,16 +12,16 @@ func RegisterUserRoutes(r *gin.Engine, db *gorm.DB) {
 	r.GET("/api/v1/user", func(c *gin.Context) {
 		model.GetUser(c, db)
 	})
-	// 创建
-	r.POST("/api/v1/user", func(c *gin.Context) {
+	// 创建，仅管理员可用
+	r.POST("/api/v1/user", middleware.AdminMiddleware(db), func(c *gin.Context) {
 		model.CreateUser(c, db)
 	})
-	// 更新
-	r.PUT("/api/v1/user/:id", func(c *gin.Context) {
+	// 更新，仅管理员可用
+	r.PUT("/api/v1/user/:id", middleware.AdminMiddleware(db), func(c *gin.Context) {
 		model.UpdateUser(c, db)
 	})
-	// 删除
-	r.DELETE("/api/v1/user/:id", func(c *gin.Context) {
+	// 删除，仅管理员可用
+	r.DELETE("/api/v1/user/:id", middleware.AdminMiddleware(db), func(c *gin.Context) {
 		model.DeleteUser(c, db)
 	})
 	// 获取当前用户信息
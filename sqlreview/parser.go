@@ -0,0 +1,304 @@
+package sqlreview
+
+import (
+	"codepub-service/sqlscan"
+	"strings"
+)
+
+// 语句类型
+const (
+	TypeSelect       = "SELECT"
+	TypeInsert       = "INSERT"
+	TypeUpdate       = "UPDATE"
+	TypeDelete       = "DELETE"
+	TypeCreateTable  = "CREATE TABLE"
+	TypeAlterTable   = "ALTER TABLE"
+	TypeDropTable    = "DROP TABLE"
+	TypeDropDatabase = "DROP DATABASE"
+	TypeTruncate     = "TRUNCATE"
+	TypeOther        = "OTHER"
+)
+
+// table 语句涉及的表，schema为空表示未指定
+type table struct {
+	schema string
+	name   string
+}
+
+func (t table) String() string {
+	if t.schema == "" {
+		return t.name
+	}
+	return t.schema + "." + t.name
+}
+
+// statement 单条语句的分析结果
+type statement struct {
+	text          string
+	typ           string
+	tables        []table
+	hasWhere      bool         // UPDATE、DELETE顶层有WHERE
+	hasLimit      bool         // SELECT顶层有LIMIT或FETCH
+	selectStar    bool         // SELECT列表中有*
+	hasPrimaryKey bool         // CREATE TABLE定义了主键
+	derived       bool         // CREATE TABLE ... AS/LIKE等，表结构来自其他表
+	nested        []*statement // WITH中修改数据的子语句，如postgres的WITH x AS (DELETE ...)
+}
+
+// parse 按分号切分语句并逐条分析
+func parse(dialect Dialect, s string, tokens []sqlscan.Token) []*statement {
+	var statements []*statement
+	begin := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].IsSymbol(";") {
+			continue
+		}
+		if i > begin {
+			statements = append(statements, analyze(dialect, s, tokens[begin:i]))
+		}
+		begin = i + 1
+	}
+	return statements
+}
+
+// analyze 分析单条语句的类型、涉及的表和规则需要的特征
+func analyze(dialect Dialect, s string, toks []sqlscan.Token) *statement {
+	st := &statement{
+		text: strings.TrimSpace(s[toks[0].Start:toks[len(toks)-1].End]),
+		typ:  TypeOther,
+	}
+	i := 0
+	// 跳过开头的括号，如 (SELECT ...) UNION (SELECT ...)
+	for i < len(toks) && toks[i].IsSymbol("(") {
+		i++
+	}
+	if i < len(toks) && toks[i].Is("WITH") {
+		begin := i + 1
+		i = sqlscan.TopLevelIndex(toks, begin, "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE")
+		end := i
+		if end < 0 {
+			end = len(toks)
+		}
+		st.nested = analyzeWith(dialect, s, toks[begin:end])
+	}
+	if i < 0 || i >= len(toks) {
+		return st
+	}
+
+	switch first := toks[i]; {
+	case first.Is("SELECT"):
+		st.typ = TypeSelect
+		analyzeSelect(st, toks, i)
+	case first.Is("INSERT", "REPLACE"):
+		st.typ = TypeInsert
+		j := skipWords(toks, i+1, "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY", "IGNORE", "INTO")
+		st.tables = parseTableList(dialect, toks, j, false)
+	case first.Is("UPDATE"):
+		st.typ = TypeUpdate
+		j := skipWords(toks, i+1, "LOW_PRIORITY", "IGNORE", "ONLY")
+		st.tables = parseTableList(dialect, toks, j, false)
+		st.hasWhere = sqlscan.TopLevelIndex(toks, i+1, "WHERE") >= 0
+	case first.Is("DELETE"):
+		st.typ = TypeDelete
+		if j := sqlscan.TopLevelIndex(toks, i+1, "FROM"); j >= 0 {
+			st.tables = parseTableList(dialect, toks, skipWords(toks, j+1, "ONLY"), false)
+		}
+		st.hasWhere = sqlscan.TopLevelIndex(toks, i+1, "WHERE") >= 0
+	case first.Is("CREATE"):
+		analyzeCreate(dialect, st, toks, i)
+	case first.Is("ALTER"):
+		j := skipWords(toks, i+1, "ONLINE", "IGNORE")
+		if j < len(toks) && toks[j].Is("TABLE") {
+			st.typ = TypeAlterTable
+			j = skipWords(toks, skipIfExists(toks, j+1), "ONLY")
+			st.tables = parseTableList(dialect, toks, j, false)
+		}
+	case first.Is("DROP"):
+		j := skipWords(toks, i+1, "TEMPORARY")
+		switch {
+		case j < len(toks) && toks[j].Is("TABLE"):
+			st.typ = TypeDropTable
+			st.tables = parseTableList(dialect, toks, skipIfExists(toks, j+1), true)
+		case j < len(toks) && toks[j].Is("DATABASE", "SCHEMA"):
+			st.typ = TypeDropDatabase
+			st.tables = parseTableList(dialect, toks, skipIfExists(toks, j+1), true)
+		}
+	case first.Is("TRUNCATE"):
+		st.typ = TypeTruncate
+		st.tables = parseTableList(dialect, toks, skipWords(toks, i+1, "TABLE", "ONLY"), true)
+	}
+	return st
+}
+
+// analyzeWith 分析WITH中各个CTE的定义，返回其中修改数据的语句，只读的SELECT不单独审核
+func analyzeWith(dialect Dialect, s string, toks []sqlscan.Token) []*statement {
+	var nested []*statement
+	depth := 0
+	for j := 0; j < len(toks); j++ {
+		switch {
+		case toks[j].IsSymbol("("):
+			if depth == 0 && j > 0 && toks[j-1].Is("AS", "MATERIALIZED") {
+				end := matchParen(toks, j)
+				body := toks[j+1 : end]
+				k := 0
+				for k < len(body) && body[k].IsSymbol("(") {
+					k++
+				}
+				if k < len(body) && body[k].Is("INSERT", "UPDATE", "DELETE", "WITH") {
+					st := analyze(dialect, s, body)
+					if st.typ != TypeSelect && st.typ != TypeOther {
+						nested = append(nested, st)
+					}
+					nested = append(nested, st.nested...)
+				}
+				j = end
+				continue
+			}
+			depth++
+		case toks[j].IsSymbol(")"):
+			depth--
+		}
+	}
+	return nested
+}
+
+// matchParen 返回与from处左括号匹配的右括号位置，没有匹配时返回len(toks)
+func matchParen(toks []sqlscan.Token, from int) int {
+	depth := 0
+	for j := from; j < len(toks); j++ {
+		switch {
+		case toks[j].IsSymbol("("):
+			depth++
+		case toks[j].IsSymbol(")"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(toks)
+}
+
+// analyzeSelect 分析顶层的SELECT列表和LIMIT，子查询和函数参数中的内容不计入
+func analyzeSelect(st *statement, toks []sqlscan.Token, from int) {
+	depth := 0
+	inList := false
+	for j := from; j < len(toks); j++ {
+		t := toks[j]
+		switch {
+		case t.IsSymbol("("):
+			depth++
+		case t.IsSymbol(")"):
+			depth--
+		case depth > 0:
+		case t.Is("SELECT"):
+			inList = true
+		case t.Is("FROM"):
+			inList = false
+		case t.Is("LIMIT", "FETCH"):
+			st.hasLimit = true
+		case inList && t.IsSymbol("*"):
+			// 排除乘法运算，*前面为SELECT、DISTINCT、逗号或表名.
+			prev := toks[j-1]
+			if prev.Is("SELECT", "DISTINCT", "ALL", "DISTINCTROW") || prev.IsSymbol(",") || prev.IsSymbol(".") {
+				st.selectStar = true
+			}
+		}
+	}
+}
+
+// analyzeCreate 分析CREATE TABLE的表名和主键，其他CREATE语句归为OTHER
+func analyzeCreate(dialect Dialect, st *statement, toks []sqlscan.Token, i int) {
+	j := skipWords(toks, i+1, "OR", "REPLACE", "TEMPORARY", "TEMP", "UNLOGGED", "GLOBAL", "LOCAL")
+	if j >= len(toks) || !toks[j].Is("TABLE") {
+		return
+	}
+	st.typ = TypeCreateTable
+	j = skipIfExists(toks, j+1)
+	t, j, ok := parseTable(dialect, toks, j)
+	if !ok {
+		return
+	}
+	st.tables = []table{t}
+
+	// CREATE TABLE ... AS SELECT、LIKE、PARTITION OF、OF type的表结构来自其他对象，不检查主键
+	if j < len(toks) && toks[j].Is("AS", "LIKE", "SELECT", "PARTITION", "OF") ||
+		j+1 < len(toks) && toks[j].IsSymbol("(") && toks[j+1].Is("LIKE") ||
+		sqlscan.TopLevelIndex(toks, j, "AS", "SELECT") >= 0 {
+		st.derived = true
+		return
+	}
+	for k := j; k+1 < len(toks); k++ {
+		if toks[k].Is("PRIMARY") && toks[k+1].Is("KEY") {
+			st.hasPrimaryKey = true
+			return
+		}
+	}
+}
+
+// skipWords 跳过任意顺序出现的指定关键字
+func skipWords(toks []sqlscan.Token, i int, keywords ...string) int {
+	for i < len(toks) && toks[i].Is(keywords...) {
+		i++
+	}
+	return i
+}
+
+// skipIfExists 跳过IF EXISTS或IF NOT EXISTS
+func skipIfExists(toks []sqlscan.Token, i int) int {
+	if i < len(toks) && toks[i].Is("IF") {
+		return skipWords(toks, i+1, "NOT", "EXISTS")
+	}
+	return i
+}
+
+// parseTableList 解析表名，multi为true时解析逗号分隔的多个表名
+func parseTableList(dialect Dialect, toks []sqlscan.Token, i int, multi bool) []table {
+	var tables []table
+	for {
+		t, next, ok := parseTable(dialect, toks, i)
+		if !ok {
+			return tables
+		}
+		tables = append(tables, t)
+		if !multi || next >= len(toks) || !toks[next].IsSymbol(",") {
+			return tables
+		}
+		i = next + 1
+	}
+}
+
+// parseTable 解析[schema.]name形式的表名，返回表名和下一个token的位置
+func parseTable(dialect Dialect, toks []sqlscan.Token, i int) (table, int, bool) {
+	var parts []string
+	for i < len(toks) && (toks[i].Kind == sqlscan.Word || toks[i].Kind == sqlscan.Ident) {
+		parts = append(parts, identName(dialect, toks[i]))
+		i++
+		if i+1 < len(toks) && toks[i].IsSymbol(".") {
+			i++
+			continue
+		}
+		break
+	}
+	switch len(parts) {
+	case 0:
+		return table{}, i, false
+	case 1:
+		return table{name: parts[0]}, i, true
+	default:
+		// postgres的database.schema.table只保留schema和table
+		return table{schema: parts[len(parts)-2], name: parts[len(parts)-1]}, i, true
+	}
+}
+
+// identName 返回标识符名称，去掉引号；postgres未加引号的标识符转为小写
+func identName(dialect Dialect, t sqlscan.Token) string {
+	if t.Kind == sqlscan.Ident {
+		quote := t.Text[:1]
+		return strings.ReplaceAll(t.Text[1:len(t.Text)-1], quote+quote, quote)
+	}
+	if dialect == Postgres {
+		return strings.ToLower(t.Text)
+	}
+	return t.Text
+}
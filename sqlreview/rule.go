@@ -0,0 +1,268 @@
+package sqlreview
+
+import (
+	"codepub-service/sqlscan"
+	"fmt"
+	"strings"
+)
+
+// Dialect sql方言，与sqlscan共用
+type Dialect = sqlscan.Dialect
+
+const (
+	MySQL    = sqlscan.MySQL
+	Postgres = sqlscan.Postgres
+)
+
+// Severity 规则级别，error阻止执行，warning需要确认后执行
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// RuleSyntax sql无法解析时的规则id，始终为error且不可关闭
+const RuleSyntax = "syntax"
+
+// Rule 审核规则及其默认配置
+type Rule struct {
+	ID          string   `json:"rule"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity"`
+	Threshold   int64    `json:"threshold,omitempty"`
+	check       func(r *reviewer, st *statement, threshold int64) []string
+}
+
+// RuleConfig 实例对规则的自定义配置，Threshold<=0时使用默认值
+type RuleConfig struct {
+	Enabled   bool
+	Severity  Severity
+	Threshold int64
+}
+
+// TableRowsFunc 获取表的估算行数，schema为空表示未指定
+type TableRowsFunc func(schema string, table string) (int64, error)
+
+// Issue 审核发现的问题，Statement为语句序号，从1开始
+type Issue struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+	Statement int      `json:"statement"`
+	Type      string   `json:"type"`
+	SQL       string   `json:"sql"`
+}
+
+// Result 审核结果
+type Result struct {
+	Statements int     `json:"statements"`
+	Errors     int     `json:"errors"`
+	Warnings   int     `json:"warnings"`
+	Issues     []Issue `json:"issues"`
+}
+
+// Rules 内置规则
+var Rules = []Rule{
+	{
+		ID:          "drop_database",
+		Description: "禁止DROP DATABASE/SCHEMA",
+		Severity:    SeverityError,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeDropDatabase {
+				return nil
+			}
+			return []string{"DROP DATABASE/SCHEMA " + joinTables(st.tables) + " is not allowed"}
+		},
+	},
+	{
+		ID:          "drop_table",
+		Description: "禁止DROP TABLE",
+		Severity:    SeverityError,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeDropTable {
+				return nil
+			}
+			return []string{"DROP TABLE " + joinTables(st.tables) + " is not allowed"}
+		},
+	},
+	{
+		ID:          "truncate",
+		Description: "禁止TRUNCATE",
+		Severity:    SeverityError,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeTruncate {
+				return nil
+			}
+			return []string{"TRUNCATE " + joinTables(st.tables) + " is not allowed"}
+		},
+	},
+	{
+		ID:          "delete_without_where",
+		Description: "DELETE必须带WHERE条件",
+		Severity:    SeverityError,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeDelete || st.hasWhere {
+				return nil
+			}
+			return []string{"DELETE on " + joinTables(st.tables) + " has no WHERE clause"}
+		},
+	},
+	{
+		ID:          "update_without_where",
+		Description: "UPDATE必须带WHERE条件",
+		Severity:    SeverityError,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeUpdate || st.hasWhere {
+				return nil
+			}
+			return []string{"UPDATE on " + joinTables(st.tables) + " has no WHERE clause"}
+		},
+	},
+	{
+		ID:          "alter_large_table",
+		Description: "ALTER TABLE的表行数超过阈值",
+		Severity:    SeverityWarning,
+		Threshold:   1000000,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeAlterTable || r.tableRows == nil {
+				return nil
+			}
+			var messages []string
+			for _, t := range st.tables {
+				rows, err := r.rows(t)
+				if err != nil {
+					messages = append(messages, fmt.Sprintf("failed to get rows of table %s: %v", t, err))
+					continue
+				}
+				if rows >= threshold {
+					messages = append(messages, fmt.Sprintf("ALTER TABLE on %s with about %d rows (threshold %d)", t, rows, threshold))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		ID:          "create_table_without_primary_key",
+		Description: "CREATE TABLE必须定义主键",
+		Severity:    SeverityWarning,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeCreateTable || st.hasPrimaryKey || st.derived {
+				return nil
+			}
+			return []string{"CREATE TABLE " + joinTables(st.tables) + " has no primary key"}
+		},
+	},
+	{
+		ID:          "select_star_without_limit",
+		Description: "SELECT *必须带LIMIT",
+		Severity:    SeverityWarning,
+		check: func(r *reviewer, st *statement, threshold int64) []string {
+			if st.typ != TypeSelect || !st.selectStar || st.hasLimit {
+				return nil
+			}
+			return []string{"SELECT * without LIMIT"}
+		},
+	},
+}
+
+// FindRule 通过id查找内置规则
+func FindRule(id string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// ValidSeverity 判断是否为有效的规则级别
+func ValidSeverity(severity Severity) bool {
+	return severity == SeverityError || severity == SeverityWarning
+}
+
+type reviewer struct {
+	tableRows TableRowsFunc
+	cache     map[table]int64
+}
+
+// rows 获取表的估算行数，同一次审核中每个表只查询一次
+func (r *reviewer) rows(t table) (int64, error) {
+	if rows, ok := r.cache[t]; ok {
+		return rows, nil
+	}
+	rows, err := r.tableRows(t.schema, t.name)
+	if err != nil {
+		return 0, err
+	}
+	r.cache[t] = rows
+	return rows, nil
+}
+
+// Review 按方言解析sql并逐条语句执行规则，configs按规则id覆盖默认配置，tableRows为nil时跳过依赖表行数的规则
+// tableRows只在alter_large_table开启且语句为ALTER TABLE时调用，查询失败作为该规则的问题返回
+func Review(dialect Dialect, query string, configs map[string]RuleConfig, tableRows TableRowsFunc) Result {
+	result := Result{Issues: []Issue{}}
+	tokens, err := sqlscan.Tokenize(dialect, query)
+	if err != nil {
+		result.add(Issue{Rule: RuleSyntax, Severity: SeverityError, Message: err.Error(), SQL: query})
+		return result
+	}
+
+	r := &reviewer{tableRows: tableRows, cache: make(map[table]int64)}
+	statements := parse(dialect, query, tokens)
+	result.Statements = len(statements)
+	for i, top := range statements {
+		// WITH中修改数据的子语句与所在语句使用相同的序号
+		for _, st := range append([]*statement{top}, top.nested...) {
+			r.check(&result, i+1, st, configs)
+		}
+	}
+	return result
+}
+
+// check 对单条语句执行所有启用的规则
+func (r *reviewer) check(result *Result, index int, st *statement, configs map[string]RuleConfig) {
+	for _, rule := range Rules {
+		severity, threshold := rule.Severity, rule.Threshold
+		if config, ok := configs[rule.ID]; ok {
+			if !config.Enabled {
+				continue
+			}
+			if ValidSeverity(config.Severity) {
+				severity = config.Severity
+			}
+			if config.Threshold > 0 {
+				threshold = config.Threshold
+			}
+		}
+		for _, message := range rule.check(r, st, threshold) {
+			result.add(Issue{
+				Rule:      rule.ID,
+				Severity:  severity,
+				Message:   message,
+				Statement: index,
+				Type:      st.typ,
+				SQL:       st.text,
+			})
+		}
+	}
+}
+
+func (r *Result) add(issue Issue) {
+	r.Issues = append(r.Issues, issue)
+	if issue.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// joinTables 表名列表，用于提示信息
+func joinTables(tables []table) string {
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.String())
+	}
+	return strings.Join(names, ", ")
+}
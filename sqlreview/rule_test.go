@@ -0,0 +1,321 @@
+package sqlreview
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// reviewRules 返回审核结果中命中的规则id，按字母排序
+func reviewRules(result Result) []string {
+	rules := []string{}
+	for _, issue := range result.Issues {
+		rules = append(rules, issue.Rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func TestReviewRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    []string
+	}{
+		// drop_database
+		{name: "drop database", dialect: MySQL, query: "DROP DATABASE shop", want: []string{"drop_database"}},
+		{name: "drop schema", dialect: Postgres, query: "DROP SCHEMA IF EXISTS app CASCADE", want: []string{"drop_database"}},
+		{name: "drop database in comment", dialect: MySQL, query: "-- DROP DATABASE shop\nSELECT 1", want: []string{}},
+		{name: "drop database in executable comment", dialect: MySQL, query: "/*!50000 DROP DATABASE shop */", want: []string{"drop_database"}},
+
+		// drop_table
+		{name: "drop table", dialect: MySQL, query: "DROP TABLE IF EXISTS `order`", want: []string{"drop_table"}},
+		{name: "drop table in string", dialect: MySQL, query: "INSERT INTO log (sql_text) VALUES ('DROP TABLE t')", want: []string{}},
+		{name: "drop table after comment", dialect: Postgres, query: "/* cleanup */ DROP TABLE t", want: []string{"drop_table"}},
+
+		// truncate
+		{name: "truncate", dialect: MySQL, query: "TRUNCATE TABLE t", want: []string{"truncate"}},
+		{name: "truncate without table keyword", dialect: Postgres, query: "truncate t", want: []string{"truncate"}},
+
+		// delete_without_where
+		{name: "delete with where", dialect: MySQL, query: "DELETE FROM t WHERE id = 1", want: []string{}},
+		{name: "delete without where", dialect: MySQL, query: "DELETE FROM t", want: []string{"delete_without_where"}},
+		{name: "delete where in line comment", dialect: MySQL, query: "DELETE FROM t -- WHERE id = 1", want: []string{"delete_without_where"}},
+		{name: "delete where in hash comment", dialect: MySQL, query: "DELETE FROM t # WHERE id = 1", want: []string{"delete_without_where"}},
+		{name: "delete where in block comment", dialect: MySQL, query: "DELETE FROM t /* where */", want: []string{"delete_without_where"}},
+		{name: "delete where in string", dialect: MySQL, query: "DELETE FROM t ORDER BY 'where' LIMIT 10", want: []string{"delete_without_where"}},
+		{name: "delete where as quoted identifier", dialect: Postgres, query: `DELETE FROM "where"`, want: []string{"delete_without_where"}},
+		{name: "delete where as backtick identifier", dialect: MySQL, query: "DELETE FROM `where`", want: []string{"delete_without_where"}},
+		{name: "delete where only in subquery", dialect: MySQL, query: "DELETE FROM t USING t JOIN (SELECT id FROM s WHERE a = 1) x", want: []string{"delete_without_where"}},
+		{name: "delete where only in cte", dialect: MySQL, query: "WITH x AS (SELECT id FROM s WHERE a = 1) DELETE FROM t", want: []string{"delete_without_where"}},
+		{name: "delete with where after cte", dialect: Postgres, query: "WITH x AS (SELECT id FROM s) DELETE FROM t WHERE id IN (SELECT id FROM x)", want: []string{}},
+		{name: "postgres delete in cte", dialect: Postgres, query: "WITH gone AS (DELETE FROM t RETURNING *) SELECT count(*) FROM gone", want: []string{"delete_without_where"}},
+		{name: "postgres delete with where in cte", dialect: Postgres, query: "WITH gone AS (DELETE FROM t WHERE id = 1 RETURNING *) SELECT * FROM gone LIMIT 1", want: []string{}},
+		{name: "postgres materialized cte with column list", dialect: Postgres, query: "WITH gone (id) AS MATERIALIZED (DELETE FROM t RETURNING id) SELECT id FROM gone", want: []string{"delete_without_where"}},
+		{name: "postgres update in second cte", dialect: Postgres, query: "WITH a AS (SELECT 1), b AS (UPDATE t SET x = 1 RETURNING id) SELECT id FROM b", want: []string{"update_without_where"}},
+		{name: "postgres select star in cte not reviewed", dialect: Postgres, query: "WITH a AS (SELECT * FROM t) SELECT id FROM a LIMIT 1", want: []string{}},
+		{name: "postgres dollar quoted where", dialect: Postgres, query: "DELETE FROM t RETURNING $$ WHERE $$", want: []string{"delete_without_where"}},
+
+		// update_without_where
+		{name: "update with where", dialect: MySQL, query: "UPDATE t SET a = 1 WHERE id = 1", want: []string{}},
+		{name: "update without where", dialect: MySQL, query: "UPDATE t SET a = 1", want: []string{"update_without_where"}},
+		{name: "update where in comment", dialect: Postgres, query: "UPDATE t SET a = 1 -- WHERE id = 1", want: []string{"update_without_where"}},
+		{name: "update where in string", dialect: Postgres, query: "UPDATE t SET note = 'where id = 1'", want: []string{"update_without_where"}},
+		{name: "update where only in subquery", dialect: MySQL, query: "UPDATE t SET a = (SELECT max(b) FROM s WHERE s.id = 1)", want: []string{"update_without_where"}},
+		{name: "postgres escape string", dialect: Postgres, query: `UPDATE t SET note = E'it\'s where'`, want: []string{"update_without_where"}},
+
+		// create_table_without_primary_key
+		{name: "create table inline primary key", dialect: MySQL, query: "CREATE TABLE t (id BIGINT PRIMARY KEY, name VARCHAR(32))", want: []string{}},
+		{name: "create table table-level primary key", dialect: MySQL, query: "CREATE TABLE t (id BIGINT, name VARCHAR(32), PRIMARY KEY (id))", want: []string{}},
+		{name: "create table named primary key constraint", dialect: Postgres, query: "CREATE TABLE t (id bigint, CONSTRAINT pk_t PRIMARY KEY (id))", want: []string{}},
+		{name: "create table without primary key", dialect: MySQL, query: "CREATE TABLE t (id BIGINT, name VARCHAR(32))", want: []string{"create_table_without_primary_key"}},
+		{name: "create table primary key in comment", dialect: MySQL, query: "CREATE TABLE t (id BIGINT /* PRIMARY KEY */, name VARCHAR(32))", want: []string{"create_table_without_primary_key"}},
+		{name: "create table primary key in column comment", dialect: MySQL, query: "CREATE TABLE t (id BIGINT COMMENT 'primary key')", want: []string{"create_table_without_primary_key"}},
+		{name: "create table like", dialect: MySQL, query: "CREATE TABLE t2 LIKE t", want: []string{}},
+		{name: "create table as select", dialect: MySQL, query: "CREATE TABLE t2 AS SELECT * FROM t", want: []string{}},
+		{name: "create table select", dialect: MySQL, query: "CREATE TABLE t2 SELECT * FROM t", want: []string{}},
+
+		// select_star_without_limit
+		{name: "select star", dialect: MySQL, query: "SELECT * FROM t", want: []string{"select_star_without_limit"}},
+		{name: "select star with limit", dialect: MySQL, query: "SELECT * FROM t LIMIT 10", want: []string{}},
+		{name: "select columns", dialect: MySQL, query: "SELECT id, name FROM t", want: []string{}},
+		{name: "select count star", dialect: MySQL, query: "SELECT count(*) FROM t", want: []string{}},
+		{name: "select star limit in subquery", dialect: MySQL, query: "SELECT * FROM (SELECT id FROM t LIMIT 10) x", want: []string{"select_star_without_limit"}},
+		{name: "select star limit in comment", dialect: MySQL, query: "SELECT * FROM t -- LIMIT 10", want: []string{"select_star_without_limit"}},
+		{name: "postgres select star fetch first", dialect: Postgres, query: "SELECT * FROM t FETCH FIRST 10 ROWS ONLY", want: []string{}},
+
+		// 多条语句逐条审核
+		{name: "multi statement", dialect: MySQL, query: "SELECT 1; DELETE FROM t; UPDATE t SET a = 1 WHERE id = 1", want: []string{"delete_without_where"}},
+		{name: "multi statement semicolon in string", dialect: MySQL, query: "UPDATE t SET a = ';' WHERE id = 1; DROP TABLE t", want: []string{"drop_table"}},
+		{name: "multi statement semicolon in comment", dialect: MySQL, query: "SELECT 1 /* ; DROP TABLE t */", want: []string{}},
+		{name: "multi statement all rules", dialect: Postgres, query: "DROP TABLE a; TRUNCATE b; DELETE FROM c", want: []string{"delete_without_where", "drop_table", "truncate"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reviewRules(Review(tt.dialect, tt.query, nil, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Review(%q) rules = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewStatements(t *testing.T) {
+	result := Review(MySQL, "SELECT 1; DELETE FROM t;; -- trailing\n", nil, nil)
+	if result.Statements != 2 {
+		t.Errorf("Statements = %d, want 2", result.Statements)
+	}
+	if len(result.Issues) != 1 {
+		t.Fatalf("Issues = %v, want 1 issue", result.Issues)
+	}
+	issue := result.Issues[0]
+	if issue.Statement != 2 || issue.Type != TypeDelete || issue.SQL != "DELETE FROM t" {
+		t.Errorf("issue = %+v, want statement 2 DELETE FROM t", issue)
+	}
+	if result.Errors != 1 || result.Warnings != 0 {
+		t.Errorf("Errors = %d, Warnings = %d, want 1 and 0", result.Errors, result.Warnings)
+	}
+}
+
+func TestReviewNestedStatement(t *testing.T) {
+	result := Review(Postgres, "SELECT 1; WITH gone AS (DELETE FROM t RETURNING *) SELECT id FROM gone", nil, nil)
+	if len(result.Issues) != 1 {
+		t.Fatalf("Issues = %v, want 1 issue", result.Issues)
+	}
+	issue := result.Issues[0]
+	if issue.Statement != 2 || issue.Type != TypeDelete || issue.SQL != "DELETE FROM t RETURNING *" {
+		t.Errorf("issue = %+v, want statement 2 DELETE FROM t RETURNING *", issue)
+	}
+}
+
+func TestReviewSyntaxError(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+	}{
+		{name: "unterminated string", dialect: MySQL, query: "DELETE FROM t WHERE name = 'x"},
+		{name: "unterminated block comment", dialect: MySQL, query: "DELETE FROM t /* WHERE id = 1"},
+		{name: "unterminated quoted identifier", dialect: Postgres, query: `DELETE FROM "t`},
+		{name: "unterminated dollar quote", dialect: Postgres, query: "SELECT $tag$ x"},
+	}
+	// 语法错误始终为error，关闭规则也不影响
+	configs := map[string]RuleConfig{RuleSyntax: {Enabled: false}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Review(tt.dialect, tt.query, configs, nil)
+			if got := reviewRules(result); !reflect.DeepEqual(got, []string{RuleSyntax}) {
+				t.Fatalf("rules = %v, want [%s]", got, RuleSyntax)
+			}
+			if result.Errors != 1 {
+				t.Errorf("Errors = %d, want 1", result.Errors)
+			}
+		})
+	}
+}
+
+func TestReviewConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		configs      map[string]RuleConfig
+		wantRules    []string
+		wantErrors   int
+		wantWarnings int
+	}{
+		{
+			name:       "default severity",
+			query:      "DELETE FROM t",
+			wantRules:  []string{"delete_without_where"},
+			wantErrors: 1,
+		},
+		{
+			name:      "disabled rule",
+			query:     "DELETE FROM t",
+			configs:   map[string]RuleConfig{"delete_without_where": {Enabled: false}},
+			wantRules: []string{},
+		},
+		{
+			name:         "downgraded severity",
+			query:        "DELETE FROM t",
+			configs:      map[string]RuleConfig{"delete_without_where": {Enabled: true, Severity: SeverityWarning}},
+			wantRules:    []string{"delete_without_where"},
+			wantWarnings: 1,
+		},
+		{
+			name:       "upgraded severity",
+			query:      "SELECT * FROM t",
+			configs:    map[string]RuleConfig{"select_star_without_limit": {Enabled: true, Severity: SeverityError}},
+			wantRules:  []string{"select_star_without_limit"},
+			wantErrors: 1,
+		},
+		{
+			name:         "invalid severity keeps default",
+			query:        "SELECT * FROM t",
+			configs:      map[string]RuleConfig{"select_star_without_limit": {Enabled: true, Severity: "fatal"}},
+			wantRules:    []string{"select_star_without_limit"},
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Review(MySQL, tt.query, tt.configs, nil)
+			if got := reviewRules(result); !reflect.DeepEqual(got, tt.wantRules) {
+				t.Errorf("rules = %v, want %v", got, tt.wantRules)
+			}
+			if result.Errors != tt.wantErrors || result.Warnings != tt.wantWarnings {
+				t.Errorf("Errors = %d, Warnings = %d, want %d and %d", result.Errors, result.Warnings, tt.wantErrors, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestReviewAlterLargeTable(t *testing.T) {
+	sizes := map[string]int64{"small": 10, "big": 5000000, "app.big": 2000}
+	tests := []struct {
+		name       string
+		dialect    Dialect
+		query      string
+		configs    map[string]RuleConfig
+		lookupErr  error
+		want       []string
+		wantLookup []string
+	}{
+		{
+			name:       "small table",
+			dialect:    MySQL,
+			query:      "ALTER TABLE small ADD COLUMN c INT",
+			want:       []string{},
+			wantLookup: []string{"small"},
+		},
+		{
+			name:       "large table",
+			dialect:    MySQL,
+			query:      "ALTER TABLE big ADD COLUMN c INT",
+			want:       []string{"alter_large_table"},
+			wantLookup: []string{"big"},
+		},
+		{
+			name:       "schema qualified",
+			dialect:    Postgres,
+			query:      `ALTER TABLE IF EXISTS ONLY app."big" ADD COLUMN c int`,
+			want:       []string{},
+			wantLookup: []string{"app.big"},
+		},
+		{
+			name:       "lowered threshold",
+			dialect:    MySQL,
+			query:      "ALTER TABLE `small` ADD COLUMN c INT",
+			configs:    map[string]RuleConfig{"alter_large_table": {Enabled: true, Threshold: 5}},
+			want:       []string{"alter_large_table"},
+			wantLookup: []string{"small"},
+		},
+		{
+			name:       "same table looked up once",
+			dialect:    MySQL,
+			query:      "ALTER TABLE big ADD COLUMN c INT; ALTER TABLE big DROP COLUMN d",
+			want:       []string{"alter_large_table", "alter_large_table"},
+			wantLookup: []string{"big"},
+		},
+		{
+			name:       "lookup error reported as issue",
+			dialect:    MySQL,
+			query:      "ALTER TABLE big ADD COLUMN c INT",
+			lookupErr:  errors.New("connection refused"),
+			want:       []string{"alter_large_table"},
+			wantLookup: []string{"big"},
+		},
+		{
+			name:       "not looked up for other statements",
+			dialect:    MySQL,
+			query:      "UPDATE big SET a = 1 WHERE id = 1; CREATE TABLE t (id INT PRIMARY KEY); SELECT id FROM big",
+			want:       []string{},
+			wantLookup: []string{},
+		},
+		{
+			name:       "not looked up when disabled",
+			dialect:    MySQL,
+			query:      "ALTER TABLE big ADD COLUMN c INT",
+			configs:    map[string]RuleConfig{"alter_large_table": {Enabled: false}},
+			want:       []string{},
+			wantLookup: []string{},
+		},
+		{
+			name:       "not looked up for alter in comment",
+			dialect:    MySQL,
+			query:      "-- ALTER TABLE big ADD COLUMN c INT\nSELECT 1",
+			want:       []string{},
+			wantLookup: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := []string{}
+			tableRows := func(schema string, table string) (int64, error) {
+				name := table
+				if schema != "" {
+					name = schema + "." + table
+				}
+				lookups = append(lookups, name)
+				if tt.lookupErr != nil {
+					return 0, tt.lookupErr
+				}
+				return sizes[name], nil
+			}
+			result := Review(tt.dialect, tt.query, tt.configs, tableRows)
+			if got := reviewRules(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(lookups, tt.wantLookup) {
+				t.Errorf("lookups = %v, want %v", lookups, tt.wantLookup)
+			}
+		})
+	}
+
+	// 未提供tableRows时跳过
+	if got := reviewRules(Review(MySQL, "ALTER TABLE big ADD COLUMN c INT", nil, nil)); len(got) != 0 {
+		t.Errorf("rules without tableRows = %v, want none", got)
+	}
+}
@@ -0,0 +1,240 @@
+// Package sqlscan 按mysql、postgres方言切分sql，跳过注释并识别字符串和引号标识符，用于判断语句类型
+package sqlscan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect sql方言
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+)
+
+// Kind token类型
+type Kind int
+
+const (
+	Word   Kind = iota // 关键字或未加引号的标识符
+	Ident              // 加引号的标识符
+	String             // 字符串
+	Number             // 数字
+	Symbol             // 运算符和标点，每个字符一个token
+)
+
+// Token sql中的一个token，Start、End为在原sql中的位置
+type Token struct {
+	Kind  Kind
+	Text  string
+	Start int
+	End   int
+}
+
+// Is 判断是否为指定关键字之一，加引号的标识符不会匹配关键字
+func (t Token) Is(keywords ...string) bool {
+	if t.Kind != Word {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(t.Text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSymbol 判断是否为指定符号
+func (t Token) IsSymbol(symbol string) bool {
+	return t.Kind == Symbol && t.Text == symbol
+}
+
+// Tokenize 按方言将sql切分为token，跳过注释；字符串、引号标识符或注释未闭合时返回错误
+// mysql的/*! */可执行注释中的内容会被执行，按普通sql切分
+func Tokenize(dialect Dialect, s string) ([]Token, error) {
+	var tokens []Token
+	execComment := false
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case isSpace(c):
+			i++
+		case strings.HasPrefix(s[i:], "--") && (dialect == Postgres || i+2 == len(s) || isSpace(s[i+2])):
+			i = lineEnd(s, i)
+		case c == '#' && dialect == MySQL:
+			i = lineEnd(s, i)
+		case strings.HasPrefix(s[i:], "/*!") && dialect == MySQL:
+			// 跳过可选的版本号
+			i += 3
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			execComment = true
+		case strings.HasPrefix(s[i:], "*/") && execComment:
+			execComment = false
+			i += 2
+		case strings.HasPrefix(s[i:], "/*"):
+			end, err := commentEnd(dialect, s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '\'':
+			end, err := quotedEnd(s, i, '\'', dialect == MySQL)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Kind: String, Text: s[i:end], Start: i, End: end})
+			i = end
+		case c == '"':
+			// mysql默认双引号为字符串，postgres为标识符
+			end, err := quotedEnd(s, i, '"', dialect == MySQL)
+			if err != nil {
+				return nil, err
+			}
+			kind := Ident
+			if dialect == MySQL {
+				kind = String
+			}
+			tokens = append(tokens, Token{Kind: kind, Text: s[i:end], Start: i, End: end})
+			i = end
+		case c == '`' && dialect == MySQL:
+			end, err := quotedEnd(s, i, '`', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Kind: Ident, Text: s[i:end], Start: i, End: end})
+			i = end
+		case c == '$' && dialect == Postgres && dollarTag(s[i:]) != "":
+			tag := dollarTag(s[i:])
+			n := strings.Index(s[i+len(tag):], tag)
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string at position %d", i)
+			}
+			end := i + len(tag) + n + len(tag)
+			tokens = append(tokens, Token{Kind: String, Text: s[i:end], Start: i, End: end})
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			end := i + 1
+			for end < len(s) && (isWordPart(s[end]) || s[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: Number, Text: s[i:end], Start: i, End: end})
+			i = end
+		case isWordStart(c):
+			end := i + 1
+			for end < len(s) && isWordPart(s[end]) {
+				end++
+			}
+			// postgres E'...'转义字符串
+			if dialect == Postgres && end == i+1 && (c == 'E' || c == 'e') && end < len(s) && s[end] == '\'' {
+				strEnd, err := quotedEnd(s, end, '\'', true)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, Token{Kind: String, Text: s[i:strEnd], Start: i, End: strEnd})
+				i = strEnd
+				continue
+			}
+			tokens = append(tokens, Token{Kind: Word, Text: s[i:end], Start: i, End: end})
+			i = end
+		default:
+			tokens = append(tokens, Token{Kind: Symbol, Text: s[i : i+1], Start: i, End: i + 1})
+			i++
+		}
+	}
+	if execComment {
+		return nil, fmt.Errorf("unterminated comment")
+	}
+	return tokens, nil
+}
+
+// quotedEnd 返回从start开始的引号内容结束位置，重复的引号视为转义，backslash为true时支持反斜杠转义
+func quotedEnd(s string, start int, quote byte, backslash bool) (int, error) {
+	i := start + 1
+	for i < len(s) {
+		switch {
+		case backslash && s[i] == '\\':
+			i += 2
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i += 2
+		case s[i] == quote:
+			return i + 1, nil
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string at position %d", start)
+}
+
+// commentEnd 返回块注释的结束位置，postgres支持嵌套注释
+func commentEnd(dialect Dialect, s string, start int) (int, error) {
+	depth := 0
+	i := start
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*") && (depth == 0 || dialect == Postgres):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment at position %d", start)
+}
+
+// dollarTag 返回postgres的$tag$或$$，不是dollar引号时返回空
+func dollarTag(s string) string {
+	if len(s) < 2 || s[0] != '$' {
+		return ""
+	}
+	if s[1] == '$' {
+		return "$$"
+	}
+	if isDigit(s[1]) || !isWordStart(s[1]) {
+		return ""
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isWordStart(s[i]) && !isDigit(s[i]) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// lineEnd 返回单行注释的结束位置
+func lineEnd(s string, start int) int {
+	if n := strings.IndexByte(s[start:], '\n'); n >= 0 {
+		return start + n + 1
+	}
+	return len(s)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWordStart 标识符首字符，非ASCII字符按标识符处理
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
@@ -0,0 +1,47 @@
+package sqlscan
+
+// ReturnsRows 判断sql的第一条语句是否返回结果集：SELECT、SHOW、EXPLAIN等查询语句，
+// 或postgres顶层带RETURNING的写语句；字符串、注释和引号标识符中的内容不参与判断，无法解析时返回false
+func ReturnsRows(dialect Dialect, query string) bool {
+	tokens, err := Tokenize(dialect, query)
+	if err != nil {
+		return false
+	}
+	// 只看第一条语句
+	for i, t := range tokens {
+		if t.IsSymbol(";") {
+			tokens = tokens[:i]
+			break
+		}
+	}
+	i := 0
+	for i < len(tokens) && tokens[i].IsSymbol("(") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].Is("WITH") {
+		i = TopLevelIndex(tokens, i+1, "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "VALUES", "TABLE")
+	}
+	if i < 0 || i >= len(tokens) {
+		return false
+	}
+	if tokens[i].Is("SELECT", "SHOW", "DESC", "DESCRIBE", "EXPLAIN", "VALUES", "TABLE") {
+		return true
+	}
+	return dialect == Postgres && TopLevelIndex(tokens, i+1, "RETURNING") >= 0
+}
+
+// TopLevelIndex 从from开始查找不在括号内的第一个指定关键字，找不到时返回-1
+func TopLevelIndex(toks []Token, from int, keywords ...string) int {
+	depth := 0
+	for j := from; j < len(toks); j++ {
+		switch {
+		case toks[j].IsSymbol("("):
+			depth++
+		case toks[j].IsSymbol(")"):
+			depth--
+		case depth <= 0 && toks[j].Is(keywords...):
+			return j
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package sqlscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReturnsRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    bool
+	}{
+		{name: "select", dialect: MySQL, query: "SELECT * FROM t", want: true},
+		{name: "lowercase select", dialect: Postgres, query: "select 1", want: true},
+		{name: "show", dialect: MySQL, query: "SHOW TABLES", want: true},
+		{name: "describe", dialect: MySQL, query: "DESC t", want: true},
+		{name: "explain", dialect: Postgres, query: "EXPLAIN SELECT 1", want: true},
+		{name: "leading comments", dialect: MySQL, query: "-- note\n/* c */ # x\nSELECT 1", want: true},
+		{name: "parenthesized select", dialect: MySQL, query: "(SELECT 1) UNION (SELECT 2)", want: true},
+		{name: "with select", dialect: MySQL, query: "WITH x AS (SELECT 1) SELECT * FROM x", want: true},
+		{name: "insert", dialect: MySQL, query: "INSERT INTO t VALUES (1)", want: false},
+		{name: "update", dialect: MySQL, query: "UPDATE t SET a = 1 WHERE id = 1", want: false},
+		{name: "mysql returning in string", dialect: MySQL, query: "UPDATE t SET note = 'returning' WHERE id = 1", want: false},
+		{name: "mysql returning keyword ignored", dialect: MySQL, query: "DELETE FROM t WHERE id = 1 RETURNING id", want: false},
+		{name: "postgres returning", dialect: Postgres, query: "INSERT INTO t (a) VALUES (1) RETURNING id", want: true},
+		{name: "postgres returning in string", dialect: Postgres, query: "UPDATE t SET note = 'returning' WHERE id = 1", want: false},
+		{name: "postgres returning in comment", dialect: Postgres, query: "UPDATE t SET a = 1 WHERE id = 1 -- returning", want: false},
+		{name: "postgres returning as identifier", dialect: Postgres, query: `UPDATE t SET "returning" = 1 WHERE id = 1`, want: false},
+		{name: "postgres with delete", dialect: Postgres, query: "WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)", want: false},
+		{name: "postgres with delete returning", dialect: Postgres, query: "WITH x AS (SELECT 1) DELETE FROM t WHERE id = 1 RETURNING *", want: true},
+		{name: "postgres returning only in cte", dialect: Postgres, query: "WITH x AS (DELETE FROM t WHERE id = 1 RETURNING *) INSERT INTO log SELECT * FROM x", want: false},
+		{name: "postgres cte returning then select", dialect: Postgres, query: "WITH x AS (DELETE FROM t WHERE id = 1 RETURNING *) SELECT * FROM x", want: true},
+		{name: "first statement only", dialect: MySQL, query: "UPDATE t SET a = 1 WHERE id = 1; SELECT 1", want: false},
+		{name: "empty", dialect: MySQL, query: "  -- only comment", want: false},
+		{name: "unterminated", dialect: MySQL, query: "SELECT 'x", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReturnsRows(tt.dialect, tt.query); got != tt.want {
+				t.Errorf("ReturnsRows(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    []string
+	}{
+		{name: "comments skipped", dialect: MySQL, query: "SELECT /* a */ 1 -- b\n# c\n", want: []string{"SELECT", "1"}},
+		{name: "mysql double dash needs space", dialect: MySQL, query: "SELECT 1--1", want: []string{"SELECT", "1", "-", "-", "1"}},
+		{name: "mysql executable comment", dialect: MySQL, query: "/*!40101 SET x = 1 */", want: []string{"SET", "x", "=", "1"}},
+		{name: "mysql strings", dialect: MySQL, query: `SELECT 'a\'b', "c""d", ` + "`e`", want: []string{"SELECT", `'a\'b'`, ",", `"c""d"`, ",", "`e`"}},
+		{name: "postgres nested comment", dialect: Postgres, query: "SELECT /* a /* b */ c */ 1", want: []string{"SELECT", "1"}},
+		{name: "postgres dollar quote", dialect: Postgres, query: "SELECT $f$ ; -- $f$", want: []string{"SELECT", "$f$ ; -- $f$"}},
+		{name: "postgres escape string", dialect: Postgres, query: `SELECT E'a\'b'`, want: []string{"SELECT", `E'a\'b'`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.dialect, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(tokens))
+			for _, token := range tokens {
+				got = append(got, token.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	for _, query := range []string{"SELECT 'a", "SELECT /* a", "/*! SELECT 1"} {
+		if _, err := Tokenize(MySQL, query); err == nil {
+			t.Errorf("Tokenize(%q) should fail", query)
+		}
+	}
+}